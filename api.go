@@ -28,6 +28,9 @@
 package sup
 
 import (
+	"context"
+	"time"
+
 	"go.polydawn.net/go-sup/sluice"
 )
 
@@ -36,6 +39,16 @@ import (
 */
 type Agent func(Supervisor)
 
+/*
+	Your functions, if they'd rather return errors than panic them.
+
+	A returned error is handled exactly as a panic would be -- it becomes
+	the `Writ.Err`, and a manager treats it as a child failure -- but
+	it's cheaper, since no stack is captured, and it keeps your control
+	flow ordinary.  Returning nil is a clean exit.
+*/
+type AgentE func(Supervisor) error
+
 /*
 	A Writ is the authority and the setup for a supervisor -- create one,
 	then use it to run your `Agent` function.
@@ -83,6 +96,12 @@ type Writ interface {
 	*/
 	Run(Agent) Writ
 
+	/*
+		As per `Run`, but for an agent which returns its errors.
+		A returned error is wrapped in an `ErrTaskFailed`.
+	*/
+	RunE(AgentE) Writ
+
 	/*
 		Cancel the writ.  This will cause supervisor handed to a `Run` agent
 		to move to its quitting state.
@@ -92,9 +111,9 @@ type Writ interface {
 	Cancel() Writ
 
 	/*
-		Return the error that was panicked from the running agent, or, wait
-		until the agent has returned without error (in which case the result
-		is nil).
+		Return the error that was panicked (or returned) from the running
+		agent, or, wait until the agent has returned without error (in which
+		case the result is nil).
 	*/
 	Err() error
 
@@ -102,6 +121,16 @@ type Writ interface {
 		Return a channel which will be closed when the writ becomes done.
 	*/
 	DoneCh() <-chan struct{}
+
+	/*
+		Return a channel which will be closed when the agent announces
+		it's ready, via `Supervisor.Ready`.
+
+		An agent may fail (or never bother to announce anything), in which
+		case this channel is never closed; you'll typically want to select
+		on it together with `DoneCh`.
+	*/
+	ReadyCh() <-chan struct{}
 }
 
 /*
@@ -115,20 +144,91 @@ type Writ interface {
 	Typically, `sup.NewTask()` is used only once -- at the start of your program.
 */
 func NewTask(name ...string) Writ {
-	writName := WritName{}
-	for _, n := range name {
-		writName = writName.New(n)
+	return newRootWrit(rootWritName(name), nil, newTree(TaskConfig{}))
+}
+
+/*
+	As per `NewTask`, but with configuration which applies to the whole
+	tree of tasks under it -- for example, where to send logs.
+
+	This is how libraries should configure go-sup: it doesn't affect
+	anyone else's trees.
+*/
+func NewTaskWithConfig(cfg TaskConfig, name ...string) Writ {
+	return newRootWrit(rootWritName(name), nil, newTree(cfg))
+}
+
+/*
+	Prepare a new task which answers to an existing `context.Context`:
+	when the context is cancelled (or its deadline passes), the writ is
+	cancelled.  The writ's supervisor also reports the context's deadline
+	and values.
+
+	Use this to bridge into go-sup from code that hands you a context,
+	such as a gRPC or HTTP handler.
+*/
+func NewTaskFromContext(ctx context.Context, name ...string) Writ {
+	return NewTaskFromContextWithConfig(ctx, TaskConfig{}, name...)
+}
+
+/*
+	As per `NewTaskFromContext`, with configuration as per `NewTaskWithConfig`.
+
+	If the context is (or descends from) a supervisor, the new task joins
+	that supervisor's tree, and shares its configuration; `cfg` is ignored.
+*/
+func NewTaskFromContextWithConfig(ctx context.Context, cfg TaskConfig, name ...string) Writ {
+	var wrt *writ
+	if treeOf(ctx) != nil {
+		// The context is (or descends from) a supervisor: join its tree.
+		wrt = newWrit(rootWritName(name), ctx)
+	} else {
+		wrt = newRootWrit(rootWritName(name), ctx, newTree(cfg))
+	}
+	if ctx.Done() != nil {
+		go func() {
+			select {
+			case <-ctx.Done():
+				if ctx.Err() == context.DeadlineExceeded {
+					wrt.cancel(QuitReason{Kind: QuitReason_Deadline})
+				} else {
+					wrt.cancel(QuitReason{Kind: QuitReason_ParentQuit})
+				}
+			case <-wrt.doneFuse.Selectable():
+			}
+		}()
 	}
-	return newWrit(writName)
+	return wrt
 }
 
 /*
 	The interface workers look up to in order to determine when they can retire.
+
+	A Supervisor is also a `context.Context`, which is done when the
+	supervisor quits; pass it to anything that wants a context.
 */
 type Supervisor interface {
+	context.Context
+
 	Name() WritName
 	Quit() bool
 	QuitCh() <-chan struct{}
+
+	/*
+		Says why the supervisor was told to quit: cancelled, parent quit,
+		a sibling failed (and which one), the deadline passed, or a signal
+		arrived.  Returns a reason of `QuitReason_None` if not quit yet.
+	*/
+	QuitReason() QuitReason
+
+	/*
+		Announce that the agent is initialized and serving -- for example,
+		that its listener has bound a port -- so whoever holds the writ
+		(see `Writ.ReadyCh`) can go ahead and start things that depend on it.
+
+		Calling this more than once is harmless.
+	*/
+	Ready()
 }
 
 type Manager interface {
@@ -141,14 +241,54 @@ type Manager interface {
 	*/
 	NewTask(name string) Writ
 
+	/*
+		As per `NewTask`, but the task's supervisor will quit automatically
+		when the deadline passes (or when the parent's deadline passes,
+		if that's sooner).  The deadline is reported by `Supervisor.Deadline`;
+		and if it's what made the task quit, `Writ.Err` reports an
+		`ErrDeadlineExceeded` rather than a clean exit.
+
+		A deadline that passes before the writ is run doesn't stop it
+		running: the agent just starts out quit.  (So do run it; as
+		with any writ, the manager waits for it.)  A zero deadline means
+		no deadline, beyond the parent's.  Tasks that quit because their
+		deadline passed aren't restarted.
+	*/
+	NewTaskWithDeadline(name string, deadline time.Time) Writ
+
+	/*
+		As per `NewTaskWithDeadline`, with the deadline set `timeout`
+		from now.  (Prefer deadlines where you can: they compose better.)
+	*/
+	NewTaskWithTimeout(name string, timeout time.Duration) Writ
+
+	/*
+		Launch a child as described by the spec, in a new goroutine,
+		under the manager's supervision.  The spec's restart policy and
+		shutdown timeout apply to the child (and any restarts of it).
+
+		As with `NewTask`, if the manager is no longer accepting new wards,
+		the returned writ is a no-op'er and the agent is never run.
+	*/
+	StartChild(spec ChildSpec) Writ
+
 	/*
 		Halt accepting new work, and service all existing children.
 		Errors raised by any children will cause the manager to cancel all
 		other children and raise that first error -- returning (or panicking)
 		only after all children have returned.
+
+		If the manager was configured with a restart strategy, failed
+		children are restarted instead, and `Work` keeps servicing them.
 	*/
 	Work()
 
+	/*
+		As per `Work`, but instead of panicking, returns an `ErrChildren`
+		describing every child that failed -- or nil, if none did.
+	*/
+	Wait() error
+
 	/*
 		Return a channel that will eventually return a `Writ` of a completed
 		child function.
@@ -164,24 +304,90 @@ type Manager interface {
 	*/
 	GatherChild() <-chan sluice.T
 
-	// TODO i do believe you who initialized this thing ought to be able to cancel it as well.
-	// at the same time, no you can't cancel individual supervisors its spawned for agents you've delegated, because wtf is that mate.
+	/*
+		Return a channel of child errors, delivered as each failed child
+		is gathered -- while the manager is still accepting new work,
+		not just during `Work`.  What else the manager does about them
+		is set by `ManagerOpts.ErrorPolicy`.
+
+		Only errors gathered after the first call are delivered; until
+		then, the manager doesn't hold on to them.  The channel is closed
+		once the manager is done.  If you call this at all, keep reading
+		until it's closed: errors are held until read.
+	*/
+	Errors() <-chan error
+
+	/*
+		Quit the manager: stop accepting new work, and tell all children
+		to quit -- the same as if the supervisor it reports to had quit,
+		but leaving that supervisor (and the manager's siblings) alone.
+
+		Returns immediately; use `DoneCh` or `Work` to wait for the children.
+
+		(Note there's no way to cancel individual supervisors the manager
+		has spawned for agents you've delegated to: if you want that,
+		hold onto their `Writ`.)
+	*/
+	Cancel()
+
+	/*
+		Halt accepting new work, like `Work` does, but without blocking.
+		Existing children continue undisturbed.
+	*/
+	Winddown()
+
+	/*
+		Return a channel which will be closed when the manager is done:
+		no longer accepting work, and all children have been gathered.
+	*/
+	DoneCh() <-chan struct{}
+
+	/*
+		Take a snapshot of the live children -- their names, phases,
+		start times, and restart counts -- and of any managers they've
+		created, and so on down the tree.  See `SnapshotWrit` to start
+		from the root task.
+	*/
+	Snapshot() ManagerSnapshot
 }
 
-func NewManager(reportingTo Supervisor) Manager {
-	return newManager(reportingTo)
+/*
+	Create a new manager, which will supervise tasks on behalf of the
+	agent holding `reportingTo`.
+
+	Options (see `ManagerOpts`) configure behaviors such as restarting
+	failed children; with none, any child error quits the whole manager.
+*/
+func NewManager(reportingTo Supervisor, opts ...ManagerOpt) Manager {
+	return newManager(reportingTo, opts...)
 }
 
 /*
-	Sets the log function used for internal debug messages.
+	Sets the default logger which receives lifecycle events (see `LogEvent`),
+	for trees that don't configure their own (see `TaskConfig`).
+	A nil logger discards them.
 
-	Don't call this in libraries.
+	Don't call this in libraries; use `NewTaskWithConfig` instead.
 	If you do call it in a program, do	so as early as possible;
 	if you fail to set this before starting any tasks or supervisors
 	(or spinning off any goroutines that will do so), then your logs may be
-	of mixed format, and if you have the race detector enabled you will most
-	certainly find one.
+	of mixed format.
+*/
+func SetLogger(l Logger) {
+	if l == nil {
+		l = func(LogEvent) {}
+	}
+	logger.Store(l)
+}
+
+/*
+	Sets the log function used for internal debug messages.
+	This is the old-style equivalent of `SetLogger`; see `LogFn`.
 */
 func SetLogFunction(fn LogFn) {
-	log = fn
+	if fn == nil {
+		SetLogger(nil)
+		return
+	}
+	SetLogger(fn.Logger())
 }
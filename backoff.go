@@ -0,0 +1,92 @@
+package sup
+
+import (
+	"math/rand"
+	"time"
+)
+
+/*
+	Describes how long to wait between successive attempts to run an agent.
+
+	The first retry waits `Initial`; each subsequent retry waits `Multiplier`
+	times longer than the last, up to `Max`.  `Jitter` is a fraction (e.g.
+	0.1 for 10%) by which each delay is randomly varied in either direction,
+	so that many failing tasks don't all retry in lockstep.
+
+	An attempt that runs for at least `ResetAfter` before failing counts
+	as stable: the next retry waits `Initial` again, rather than an ever
+	longer time for a task that only fails now and then.
+
+	The zero value retries immediately, every time.
+*/
+type Backoff struct {
+	Initial    time.Duration
+	Multiplier float64 // values less than 1 are treated as 1.
+	Max        time.Duration
+	Jitter     float64
+	ResetAfter time.Duration // zero means `Max`; or if that's zero too, `DefaultBackoffReset`.
+}
+
+/*
+	How long an attempt must run to reset a `Backoff` with neither
+	`ResetAfter` nor `Max` set.
+*/
+const DefaultBackoffReset = time.Minute
+
+/*
+	Returns the delay before retry number `attempt` (counting from zero).
+*/
+func (b Backoff) Delay(attempt int) time.Duration {
+	delay := float64(b.Initial)
+	for i := 0; i < attempt && (b.Max <= 0 || delay < float64(b.Max)); i++ {
+		if b.Multiplier > 1 {
+			delay *= b.Multiplier
+		}
+	}
+	if b.Max > 0 && delay > float64(b.Max) {
+		delay = float64(b.Max)
+	}
+	if b.Jitter > 0 {
+		delay += delay * b.Jitter * (2*rand.Float64() - 1)
+	}
+	if delay < 0 {
+		return 0
+	}
+	return time.Duration(delay)
+}
+
+/*
+	Returns the attempt number to use for the next retry, given the
+	previous one and how long the failed attempt ran for.
+*/
+func (b Backoff) next(attempt int, ran time.Duration) int {
+	reset := b.ResetAfter
+	if reset <= 0 {
+		reset = b.Max
+	}
+	if reset <= 0 {
+		reset = DefaultBackoffReset
+	}
+	if ran >= reset {
+		return 0
+	}
+	return attempt
+}
+
+/*
+	Wait out `delay`, unless the quit channel closes first.
+	Returns true if the full delay elapsed.
+*/
+func sleepUnlessQuit(delay time.Duration, quitCh <-chan struct{}) bool {
+	if delay <= 0 {
+		return true
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-quitCh:
+		return false
+	}
+}
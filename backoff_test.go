@@ -0,0 +1,76 @@
+package sup
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBackoff(t *testing.T) {
+	Convey("Backoff delays should grow and cap", t, func() {
+		b := Backoff{Initial: time.Second, Multiplier: 2, Max: 5 * time.Second}
+		So(b.Delay(0), ShouldEqual, 1*time.Second)
+		So(b.Delay(1), ShouldEqual, 2*time.Second)
+		So(b.Delay(2), ShouldEqual, 4*time.Second)
+		So(b.Delay(3), ShouldEqual, 5*time.Second)
+		So(b.Delay(300), ShouldEqual, 5*time.Second)
+
+		Convey("A stable run resets the attempt count", func() {
+			So(b.next(3, 4*time.Second), ShouldEqual, 3)
+			So(b.next(3, 5*time.Second), ShouldEqual, 0)
+			b.ResetAfter = time.Minute
+			So(b.next(3, 5*time.Second), ShouldEqual, 3)
+			b.Max = 0
+			b.ResetAfter = 0
+			So(b.next(3, DefaultBackoffReset), ShouldEqual, 0)
+		})
+
+		Convey("Jitter stays within bounds", func() {
+			b.Jitter = 0.5
+			for i := 0; i < 50; i++ {
+				So(b.Delay(0), ShouldBeBetweenOrEqual, 500*time.Millisecond, 1500*time.Millisecond)
+			}
+		})
+	})
+
+	Convey("Given an agent decorated with backoff", t, func() {
+		var runs int32
+		explo := fmt.Errorf("bang!")
+		b := Backoff{Initial: time.Millisecond, Multiplier: 2}
+
+		Convey("It retries until success", func() {
+			wrt := NewTask().Run(Behaviors.Backoff(b, FlakyAgent(&runs, 3, explo)))
+			So(wrt.Err(), ShouldBeNil)
+			So(atomic.LoadInt32(&runs), ShouldEqual, 4)
+		})
+
+		Convey("Attempts that ran stably don't make the wait grow", func() {
+			b := Backoff{Initial: time.Millisecond, Multiplier: 1000, ResetAfter: 5 * time.Millisecond}
+			start := time.Now()
+			wrt := NewTask().Run(Behaviors.Backoff(b, func(super Supervisor) {
+				time.Sleep(10 * time.Millisecond)
+				FlakyAgent(&runs, 3, explo)(super)
+			}))
+			So(wrt.Err(), ShouldBeNil)
+			So(atomic.LoadInt32(&runs), ShouldEqual, 4)
+			So(time.Since(start), ShouldBeLessThan, time.Second)
+		})
+
+		Convey("Quitting interrupts the wait and raises the error", func() {
+			b.Initial = time.Hour
+			wrt := NewTask()
+			go func() {
+				for atomic.LoadInt32(&runs) == 0 {
+					time.Sleep(time.Millisecond)
+				}
+				wrt.Cancel()
+			}()
+			wrt.Run(Behaviors.Backoff(b, FlakyAgent(&runs, 3, explo)))
+			So(wrt.Err(), ShouldNotBeNil)
+			So(atomic.LoadInt32(&runs), ShouldEqual, 1)
+		})
+	})
+}
@@ -1,5 +1,12 @@
 package sup
 
+import (
+	"fmt"
+	"time"
+
+	"go.polydawn.net/meep"
+)
+
 /*
 	Gathering type to hang methods off of.
 
@@ -28,3 +35,122 @@ func (x looper) Work(super Supervisor) {
 		x.Agent(super)
 	}
 }
+
+//// Backoff
+
+/*
+	Decorates an agent to be retried whenever it panics, waiting according
+	to the given `Backoff` between attempts.
+
+	Waiting is cut short if the supervisor signals it's time to quit;
+	the most recent error is then raised, just as if there had been no retry.
+	If the agent returns without error, so does the decorated agent.
+*/
+func (Behavior) Backoff(backoff Backoff, agent Agent) Agent {
+	return backoffer{backoff, agent}.Work
+}
+
+type backoffer struct {
+	Backoff
+	Agent
+}
+
+func (x backoffer) Work(super Supervisor) {
+	for attempt := 0; ; attempt++ {
+		var err error
+		started := time.Now()
+		meep.Try(func() {
+			x.Agent(super)
+		}, meep.TryPlan{
+			{CatchAny: true, Handler: func(e error) {
+				err = e
+			}},
+		})
+		if err == nil {
+			return
+		}
+		attempt = x.next(attempt, time.Since(started))
+		delay := x.Delay(attempt)
+		if !sleepUnlessQuit(delay, super.QuitCh()) || super.Quit() {
+			panic(err)
+		}
+		treeOf(super).log(LogEvent{
+			Level:    LogLevel_Info,
+			Kind:     LogKind_Retrying,
+			Name:     super.Name(),
+			Msg:      fmt.Sprintf("retrying after error: %s", err),
+			Err:      err,
+			Duration: delay,
+		})
+	}
+}
+
+//// Pool
+
+/*
+	Decorates a handler to be run on each item from the source channel,
+	by at most `n` workers at once.  Each item is handled by its own
+	supervised task (named "worker-0", "worker-1", and so on) under a
+	manager of the pool's own.
+
+	Items are only taken from the source when a worker is free, so a
+	slow pool applies backpressure to whoever is sending.
+
+	The pool runs until the source is closed, or the supervisor signals
+	it's time to quit, or a worker fails -- then it stops taking items,
+	waits for the workers it has running, and raises the first error,
+	just as `Manager.Work` does.
+*/
+func (Behavior) Pool(n int, source <-chan interface{}, handler func(Supervisor, interface{})) Agent {
+	if n < 1 {
+		panic(fmt.Sprintf("pool size must be at least 1, not %d", n))
+	}
+	return pool{n, source, handler}.Work
+}
+
+type pool struct {
+	n       int
+	source  <-chan interface{}
+	handler func(Supervisor, interface{})
+}
+
+func (x pool) Work(super Supervisor) {
+	// Workers that finish fine are reaped straight away; a long-running
+	// pool would otherwise hold every one of them until the end.
+	mgr := NewManager(super, ManagerOpts.TombstoneLimit(0))
+	slots := make(chan struct{}, x.n)
+	failed := mgr.Errors()
+TakeLoop:
+	for i := 0; ; i++ {
+		// Wait for a free worker before taking an item.
+		select {
+		case slots <- struct{}{}:
+		case <-failed:
+			break TakeLoop
+		case <-super.QuitCh():
+			break TakeLoop
+		}
+		select {
+		case item, ok := <-x.source:
+			if !ok {
+				break TakeLoop
+			}
+			wrt := mgr.NewTask(fmt.Sprintf("worker-%d", i))
+			go func() {
+				defer func() { <-slots }()
+				wrt.Run(func(super Supervisor) { x.handler(super, item) })
+			}()
+		case <-failed:
+			break TakeLoop
+		case <-super.QuitCh():
+			break TakeLoop
+		}
+	}
+	// `Work` raises the first failure; drain the stream so that later
+	// ones don't hold it open.
+	go func() {
+		for range failed {
+		}
+	}()
+	mgr.Work()
+}
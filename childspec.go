@@ -0,0 +1,142 @@
+package sup
+
+import (
+	"fmt"
+	"time"
+
+	"go.polydawn.net/meep"
+)
+
+/*
+	Declares a child task for a manager, so that supervision trees can be
+	described (and checked) up front, rather than assembled by hand with
+	`go mgr.NewTask("x").Run(fn)` lines.
+
+	Use `NewSupervisorTree` to run a whole list of these, or
+	`Manager.StartChild` to launch them one at a time.
+*/
+type ChildSpec struct {
+	// The name for the child's writ.  Must be unique among its siblings.
+	Name string
+
+	// The work to do.
+	Agent Agent
+
+	// When to restart the child (if the manager has a restart strategy).
+	// The zero value is `RestartPolicy_Transient`.
+	Restart RestartPolicy
+
+	// Set this if the agent calls `Supervisor.Ready` once it's initialized;
+	// ordered managers will wait for that before starting the next child.
+	// Otherwise, the child is considered ready as soon as it starts running.
+	AwaitReady bool
+
+	// How long the child may take to return after being told to quit,
+	// before the manager abandons it.  (See `ManagerOpts.ShutdownTimeout`.)
+	// Zero means defer to the manager's shutdown timeout, if any.
+	ShutdownTimeout time.Duration
+}
+
+/*
+	Describes when a child should be restarted after it returns.
+
+		- Transient: restarted only if it failed.  (This is also how children
+		  started with `Manager.NewTask` are treated.)
+		- Permanent: restarted whenever it returns, even without error,
+		  unless it was told to quit.
+		- Temporary: never restarted -- not even when one of its siblings
+		  fails and takes it down under a one-for-all or rest-for-one strategy.
+
+	Restart policies only take effect if the manager has a restart strategy.
+	A child error that doesn't lead to a restart is raised by `Work` as usual.
+*/
+type RestartPolicy int
+
+const (
+	RestartPolicy_Transient RestartPolicy = iota
+	RestartPolicy_Permanent
+	RestartPolicy_Temporary
+)
+
+func (policy RestartPolicy) String() string {
+	switch policy {
+	case RestartPolicy_Transient:
+		return "transient"
+	case RestartPolicy_Permanent:
+		return "permanent"
+	case RestartPolicy_Temporary:
+		return "temporary"
+	default:
+		return fmt.Sprintf("RestartPolicy(%d)", int(policy))
+	}
+}
+
+/*
+	Checks a set of child specs for mistakes: missing names or agents,
+	duplicate names, unknown restart policies, and negative timeouts.
+	Returns an `ErrInvalidChildSpec` describing the first problem found,
+	or nil.
+*/
+func ValidateChildSpecs(specs ...ChildSpec) error {
+	seen := make(map[string]struct{}, len(specs))
+	for i, spec := range specs {
+		var problem string
+		switch {
+		case spec.Name == "":
+			problem = "name must not be empty"
+		case spec.Agent == nil:
+			problem = "agent must not be nil"
+		case spec.Restart < RestartPolicy_Transient || spec.Restart > RestartPolicy_Temporary:
+			problem = fmt.Sprintf("unknown restart policy %d", spec.Restart)
+		case spec.ShutdownTimeout < 0:
+			problem = "shutdown timeout must not be negative"
+		}
+		if _, dup := seen[spec.Name]; dup && problem == "" {
+			problem = "name is already used by a sibling"
+		}
+		if problem != "" {
+			return meep.Meep(&ErrInvalidChildSpec{Index: i, Name: spec.Name, Problem: problem})
+		}
+		seen[spec.Name] = struct{}{}
+	}
+	return nil
+}
+
+/*
+	The restart intensity `NewSupervisorTree` uses unless told otherwise:
+	at most this many restarts in this period, as in OTP.
+*/
+const (
+	DefaultTreeIntensity = 3
+	DefaultTreePeriod    = 5 * time.Second
+)
+
+/*
+	Build a static supervision tree: create a `Manager` reporting to `super`,
+	start each child in the order given, and `Work` until they're all done.
+
+	The manager uses `RestartStrategy_OneForOne`, so each child is restarted
+	according to its own `RestartPolicy`; and it's `Ordered`, so each child
+	is ready before the next is started, and children are shut down in
+	reverse order.  Its restart intensity defaults to `DefaultTreeIntensity`
+	restarts in `DefaultTreePeriod`, so a child that fails on start makes
+	the tree give up rather than spin.  Any options given are applied
+	after these, so they can change any of it.
+
+	The specs are validated before anything is started; if they're invalid,
+	the `ErrInvalidChildSpec` is panicked, like any other task error.
+*/
+func NewSupervisorTree(super Supervisor, specs []ChildSpec, opts ...ManagerOpt) {
+	if err := ValidateChildSpecs(specs...); err != nil {
+		panic(err)
+	}
+	mgr := NewManager(super, append([]ManagerOpt{
+		ManagerOpts.Restart(RestartStrategy_OneForOne),
+		ManagerOpts.RestartIntensity(DefaultTreeIntensity, DefaultTreePeriod),
+		ManagerOpts.Ordered(),
+	}, opts...)...)
+	for _, spec := range specs {
+		mgr.StartChild(spec)
+	}
+	mgr.Work()
+}
@@ -0,0 +1,88 @@
+package sup
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestChildSpecs(t *testing.T) {
+	Convey("Child specs can be validated", t, func() {
+		noop := func(Supervisor) {}
+
+		Convey("Good specs pass", func() {
+			err := ValidateChildSpecs(
+				ChildSpec{Name: "a", Agent: noop},
+				ChildSpec{Name: "b", Agent: noop, Restart: RestartPolicy_Permanent},
+			)
+			So(err, ShouldBeNil)
+		})
+
+		Convey("Duplicate names are rejected", func() {
+			err := ValidateChildSpecs(
+				ChildSpec{Name: "a", Agent: noop},
+				ChildSpec{Name: "a", Agent: noop},
+			)
+			So(err, ShouldHaveSameTypeAs, &ErrInvalidChildSpec{})
+			So(err.(*ErrInvalidChildSpec).Index, ShouldEqual, 1)
+		})
+
+		Convey("Missing agents are rejected", func() {
+			err := ValidateChildSpecs(ChildSpec{Name: "a"})
+			So(err, ShouldHaveSameTypeAs, &ErrInvalidChildSpec{})
+		})
+
+		Convey("NewSupervisorTree panics before starting anything", func() {
+			var runs int32
+			wrt := NewTask().Run(func(super Supervisor) {
+				NewSupervisorTree(super, []ChildSpec{
+					{Name: "a", Agent: CountingAgent(&runs)},
+					{Name: "", Agent: noop},
+				})
+			})
+			So(wrt.Err(), ShouldNotBeNil)
+			So(atomic.LoadInt32(&runs), ShouldEqual, 0)
+		})
+	})
+
+	Convey("Given a supervision tree", t, func() {
+		rootWrit := NewTask()
+
+		Convey("Permanent children are restarted until told to quit", func() {
+			var permRuns, tempRuns, transRuns int32
+			rootWrit.Run(func(super Supervisor) {
+				NewSupervisorTree(super, []ChildSpec{
+					{Name: "perm", Restart: RestartPolicy_Permanent, Agent: func(super Supervisor) {
+						if atomic.AddInt32(&permRuns, 1) == 3 {
+							for atomic.LoadInt32(&transRuns) < 2 {
+								time.Sleep(time.Millisecond)
+							}
+							rootWrit.Cancel()
+							<-super.QuitCh()
+						}
+					}},
+					{Name: "temp", Restart: RestartPolicy_Temporary, Agent: CountingAgent(&tempRuns)},
+					{Name: "trans", Agent: FlakyAgent(&transRuns, 1, fmt.Errorf("bang!"))},
+				}, ManagerOpts.RestartIntensity(10, time.Minute))
+			})
+			So(rootWrit.Err(), ShouldBeNil)
+			So(atomic.LoadInt32(&permRuns), ShouldEqual, 3)
+			So(atomic.LoadInt32(&tempRuns), ShouldEqual, 1)
+			So(atomic.LoadInt32(&transRuns), ShouldEqual, 2)
+		})
+
+		Convey("A child that keeps failing makes the tree give up", func() {
+			var runs int32
+			rootWrit.Run(func(super Supervisor) {
+				NewSupervisorTree(super, []ChildSpec{
+					{Name: "doomed", Agent: FlakyAgent(&runs, 1000, fmt.Errorf("bang!"))},
+				})
+			})
+			So(rootWrit.Err(), ShouldNotBeNil)
+			So(atomic.LoadInt32(&runs), ShouldEqual, DefaultTreeIntensity+1)
+		})
+	})
+}
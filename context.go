@@ -1,28 +1,118 @@
 package sup
 
 import (
+	"context"
 	"time"
 )
 
 /*
-	Copy of the stdlib (or `x/net`, in older go versions) `Context` interface.
+	Every `Supervisor` is a `context.Context`: agents can hand theirs
+	directly to `net/http`, `database/sql`, `os/exec`, and so on,
+	and those calls will be cancelled when the agent is told to quit.
 
-	Cloned here simply so we can assert that we implement it (without requiring
-	either that package import or go1.7 for build) -- and not exported because
-	if you care about this, use those packages to refer to it.
-
-	Upstream: https://tip.golang.org/src/context/context.go
+	- `Done` is the same channel as `QuitCh`.
+	- `Err` is `context.Canceled` once the supervisor has quit --
+	  or `context.DeadlineExceeded` if its deadline is what made it quit,
+	  or if the parent context is done, whatever the parent says.
+	- `Value` looks up values in the parent: the supervisor of the agent
+	  which created the manager (and so on up the tree).
+	  (Internally, it also answers for its own writ: that's how a new
+	  manager finds the writ it's working under.  And if the tree has a
+	  `Tracer`, it answers for the task's span: see `Tracer`.)
+	- `Deadline` reports the writ's deadline (see `Manager.NewTaskWithDeadline`),
+	  or else the parent's deadline, if any.
 */
-type context interface {
-	// Returns a deadline, if there is one.  (go-sup doesn't use these.)
-	Deadline() (deadline time.Time, ok bool)
+var _ context.Context = &supervisor{}
+
+type writKey struct{}
+
+func (super *supervisor) Deadline() (deadline time.Time, ok bool) {
+	if !super.writ.deadline.IsZero() {
+		return super.writ.deadline, true
+	}
+	if super.parent == nil {
+		return
+	}
+	return super.parent.Deadline()
+}
+
+func (super *supervisor) Done() <-chan struct{} {
+	return super.ctrlChan_quit.Selectable()
+}
+
+func (super *supervisor) Err() error {
+	if !super.ctrlChan_quit.IsBlown() {
+		return nil
+	}
+	if super.writ.reason().Kind == QuitReason_Deadline {
+		return context.DeadlineExceeded
+	}
+	if super.parent != nil {
+		if err := super.parent.Err(); err != nil {
+			return err
+		}
+	}
+	return context.Canceled
+}
+
+func (super *supervisor) Value(key interface{}) interface{} {
+	if key == (writKey{}) {
+		return super.writ
+	}
+	super.writ.mu.Lock()
+	spanCtx := super.writ.spanCtx
+	super.writ.mu.Unlock()
+	if spanCtx != nil {
+		// Derived from the parent, with our tracing span added.
+		return spanCtx.Value(key)
+	}
+	if super.parent == nil {
+		return nil
+	}
+	return super.parent.Value(key)
+}
+
+//// deadlines
 
-	// Select on this to know when you should return.
-	Done() <-chan struct{}
+/*
+	Give the writ a deadline -- or the parent's deadline, if that's sooner.
+	When the deadline passes, the writ expires: it's cancelled, and its
+	`Err` will report `ErrDeadlineExceeded` (unless the agent raises
+	some other error).
 
-	// Name and type somewhat confusing: this is a nilipotent checker for if `Done` already happened
-	Err() error
+	Must be called before the writ is released to anyone else.
+*/
+func (writ *writ) setDeadline(deadline time.Time) {
+	if parent := writ.svr.(*supervisor).parent; parent != nil {
+		if parentDeadline, ok := parent.Deadline(); ok && parentDeadline.Before(deadline) {
+			deadline = parentDeadline
+		}
+	}
+	writ.deadline = deadline
+	remaining := deadline.Sub(time.Now())
+	if remaining <= 0 {
+		writ.expire()
+		return
+	}
+	writ.mu.Lock()
+	writ.deadlineTimer = time.AfterFunc(remaining, writ.expire)
+	writ.mu.Unlock()
+}
+
+/*
+	Tell the writ to quit because its deadline passed.  As when a manager
+	cancels a ward, the phase is left alone: a writ that hasn't been run
+	yet still runs (already quit), and turns in to its manager as usual.
+*/
+func (writ *writ) expire() {
+	writ.quit(QuitReason{Kind: QuitReason_Deadline})
+}
 
-	// Bag for values.  (go-sup doesn't use these.)  https://blog.golang.org/context has suggested usage.
-	Value(key interface{}) interface{}
+func (writ *writ) stopDeadline() {
+	writ.mu.Lock()
+	timer := writ.deadlineTimer
+	writ.mu.Unlock()
+	if timer != nil {
+		timer.Stop()
+	}
 }
@@ -0,0 +1,200 @@
+package sup
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSupervisorContext(t *testing.T) {
+	Convey("Supervisors are contexts", t, func() {
+		wrt := NewTask()
+		ready := make(chan struct{})
+		var derived context.Context
+		var errBeforeQuit, errAfterQuit, derivedErr error
+		go wrt.Run(func(super Supervisor) {
+			var cancel context.CancelFunc
+			derived, cancel = context.WithCancel(super)
+			defer cancel()
+			errBeforeQuit = super.Err()
+			close(ready)
+			<-derived.Done()
+			errAfterQuit = super.Err()
+			derivedErr = derived.Err()
+		})
+		<-ready
+		wrt.Cancel()
+		So(wrt.Err(), ShouldBeNil)
+
+		Convey("Quitting cancels derived contexts", func() {
+			So(errBeforeQuit, ShouldBeNil)
+			So(errAfterQuit, ShouldEqual, context.Canceled)
+			So(derivedErr, ShouldEqual, context.Canceled)
+		})
+	})
+
+	Convey("Tasks can answer to an external context", t, func() {
+		type key string
+		ctx, cancel := context.WithCancel(context.WithValue(context.Background(), key("k"), "v"))
+		wrt := NewTaskFromContext(ctx, "handler")
+		var seen interface{}
+		var errAfterQuit error
+		go func() {
+			<-wrt.ReadyCh()
+			cancel()
+		}()
+		wrt.Run(func(super Supervisor) {
+			seen = super.Value(key("k"))
+			super.Ready()
+			<-super.QuitCh()
+			errAfterQuit = super.Err()
+		})
+		So(wrt.Name().String(), ShouldEqual, "handler")
+		So(seen, ShouldEqual, "v")
+		So(errAfterQuit, ShouldEqual, context.Canceled)
+
+		Convey("Already-cancelled contexts cancel the task before it starts", func() {
+			var ran bool
+			wrt := NewTaskFromContext(ctx)
+			<-wrt.DoneCh()
+			wrt.Run(func(Supervisor) { ran = true })
+			So(ran, ShouldBeFalse)
+		})
+	})
+
+	Convey("Children see values from further up the tree", t, func() {
+		type key string
+		var seen interface{}
+		NewTask().Run(func(super Supervisor) {
+			mgr := NewManager(ctxValueSupervisor{super, key("k"), "v"})
+			mgr.NewTask("child").Run(func(super Supervisor) {
+				seen = super.Value(key("k"))
+			})
+			mgr.Work()
+		})
+		So(seen, ShouldEqual, "v")
+	})
+}
+
+// Wraps a supervisor to answer one extra value.
+type ctxValueSupervisor struct {
+	Supervisor
+	key, val interface{}
+}
+
+func (s ctxValueSupervisor) Value(key interface{}) interface{} {
+	if key == s.key {
+		return s.val
+	}
+	return s.Supervisor.Value(key)
+}
+
+func TestDeadlines(t *testing.T) {
+	Convey("Given a Manager", t, func() {
+		NewTask().Run(func(super Supervisor) {
+			mgr := NewManager(super)
+
+			Convey("A task quits when its deadline passes", func() {
+				deadline := time.Now().Add(10 * time.Millisecond)
+				var seenDeadline time.Time
+				var seenErr error
+				wrt := mgr.NewTaskWithDeadline("slow", deadline)
+				wrt.Run(func(super Supervisor) {
+					seenDeadline, _ = super.Deadline()
+					<-super.QuitCh()
+					seenErr = super.Err()
+				})
+				So(seenDeadline.Equal(deadline), ShouldBeTrue)
+				So(seenErr == context.DeadlineExceeded, ShouldBeTrue)
+				So(wrt.Err(), ShouldHaveSameTypeAs, &ErrDeadlineExceeded{})
+				So(mgr.Wait(), ShouldNotBeNil)
+			})
+
+			Convey("A task finishing in time is a clean exit", func() {
+				wrt := mgr.NewTaskWithTimeout("quick", time.Hour)
+				wrt.Run(func(Supervisor) {})
+				So(wrt.Err(), ShouldBeNil)
+				So(mgr.Wait(), ShouldBeNil)
+			})
+
+			Convey("Cancelling is not the same as expiring", func() {
+				wrt := mgr.NewTaskWithTimeout("cancelled", time.Hour)
+				var seenErr error
+				wrt.Run(func(super Supervisor) {
+					wrt.Cancel()
+					seenErr = super.Err()
+				})
+				So(seenErr, ShouldEqual, context.Canceled)
+				So(wrt.Err(), ShouldBeNil)
+				mgr.Wait()
+			})
+
+			Convey("A task run after its deadline still turns in", func() {
+				wrt := mgr.NewTaskWithTimeout("late", time.Millisecond)
+				time.Sleep(20 * time.Millisecond)
+				var quit bool
+				wrt.Run(func(super Supervisor) { quit = super.Quit() })
+				So(quit, ShouldBeTrue)
+				So(wrt.Err(), ShouldHaveSameTypeAs, &ErrDeadlineExceeded{})
+				err := mgr.Wait()
+				So(err, ShouldNotBeNil)
+				So(err.(*ErrChildren).Failures[0].Err, ShouldHaveSameTypeAs, &ErrDeadlineExceeded{})
+			})
+
+			Convey("So does a task whose deadline had already passed", func() {
+				wrt := mgr.NewTaskWithDeadline("past", time.Now().Add(-time.Second))
+				go wrt.Run(func(Supervisor) {})
+				So(wrt.Err(), ShouldHaveSameTypeAs, &ErrDeadlineExceeded{})
+				So(mgr.Wait(), ShouldNotBeNil)
+			})
+
+			Convey("Children inherit the parent's deadline if it's sooner", func() {
+				var seenDeadline time.Time
+				parentDeadline := time.Now().Add(time.Minute)
+				mgr.NewTaskWithDeadline("parent", parentDeadline).Run(func(super Supervisor) {
+					mgr := NewManager(super)
+					mgr.NewTaskWithTimeout("child", time.Hour).Run(func(super Supervisor) {
+						seenDeadline, _ = super.Deadline()
+					})
+					mgr.Work()
+				})
+				So(seenDeadline.Equal(parentDeadline), ShouldBeTrue)
+				mgr.Work()
+			})
+
+			Convey("Children whose parent's deadline passed before they ran still turn in", func() {
+				var ran bool
+				mgr.NewTaskWithTimeout("parent", 5*time.Millisecond).Run(func(super Supervisor) {
+					mgr := NewManager(super)
+					wrt := mgr.NewTaskWithTimeout("child", time.Hour)
+					time.Sleep(20 * time.Millisecond)
+					wrt.Run(func(Supervisor) { ran = true })
+					mgr.Wait()
+				})
+				So(ran, ShouldBeTrue)
+				So(mgr.Wait(), ShouldNotBeNil)
+			})
+		})
+	})
+
+	Convey("Given a Manager with a restart strategy", t, func() {
+		NewTask().Run(func(super Supervisor) {
+			mgr := NewManager(super, ManagerOpts.Restart(RestartStrategy_OneForOne))
+
+			Convey("An expired task isn't restarted", func() {
+				var runs int32
+				go mgr.NewTaskWithTimeout("slow", 5*time.Millisecond).Run(func(super Supervisor) {
+					atomic.AddInt32(&runs, 1)
+					<-super.QuitCh()
+				})
+				err := mgr.Wait()
+				So(err, ShouldNotBeNil)
+				So(err.(*ErrChildren).Failures[0].Err, ShouldHaveSameTypeAs, &ErrDeadlineExceeded{})
+				So(atomic.LoadInt32(&runs), ShouldEqual, 1)
+			})
+		})
+	})
+}
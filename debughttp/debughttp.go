@@ -0,0 +1,169 @@
+/*
+	`debughttp` provides an `http.Handler` which renders a live view of a
+	supervision tree -- every task, its phase, how long it's been running,
+	and how many times it's been restarted -- as HTML, JSON, or plain text.
+
+	Tasks that have been told to quit and haven't returned within their
+	expected shutdown time are highlighted: these are usually the ones
+	you're looking for.
+
+	Mount it next to `net/http/pprof` on your admin port:
+
+		http.Handle("/debug/sup", debughttp.New(rootWrit))
+*/
+package debughttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.polydawn.net/go-sup"
+)
+
+/*
+	The shutdown time expected of tasks which don't have a shutdown timeout.
+	This matches the point at which managers start logging warnings about
+	children that haven't returned.
+*/
+const DefaultExpectedShutdown = 2 * time.Second
+
+type Handler struct {
+	// The root task of the tree to render.
+	Root sup.Writ
+
+	// How long a task with no shutdown timeout may spend quitting before
+	// it's highlighted.  Zero means `DefaultExpectedShutdown`.
+	ExpectedShutdown time.Duration
+}
+
+func New(root sup.Writ) *Handler {
+	return &Handler{Root: root}
+}
+
+/*
+	Renders the tree.  The format is chosen by the `format` query parameter
+	("html", "json", or "text"); or failing that, by the Accept header;
+	or failing that, HTML.
+*/
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	view := h.view(sup.SnapshotWrit(h.Root), time.Now())
+	switch negotiate(r) {
+	case "json":
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "\t")
+		enc.Encode(view)
+	case "text":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		var buf bytes.Buffer
+		view.writeText(&buf, 0)
+		w.Write(buf.Bytes())
+	case "html":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := page.Execute(w, view); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	default:
+		http.Error(w, "unknown format", http.StatusBadRequest)
+	}
+}
+
+func negotiate(r *http.Request) string {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format
+	}
+	accept := r.Header.Get("Accept")
+	switch {
+	case strings.Contains(accept, "text/html"):
+		return "html"
+	case strings.Contains(accept, "application/json"):
+		return "json"
+	case strings.Contains(accept, "text/plain"):
+		return "text"
+	default:
+		return "html"
+	}
+}
+
+//// views
+
+/*
+	A task, as rendered: the snapshot, flattened so that children appear
+	directly under the task whose managers they belong to, plus
+	the verdict on whether it's overdue.
+*/
+type taskView struct {
+	sup.WritSnapshot            // with the managers cleared out; see Children.
+	Overdue          bool       // quitting for longer than expected.
+	Children         []taskView `json:",omitempty"`
+}
+
+func (h *Handler) view(snap sup.WritSnapshot, now time.Time) taskView {
+	expected := snap.ShutdownTimeout
+	if expected <= 0 {
+		expected = h.ExpectedShutdown
+	}
+	if expected <= 0 {
+		expected = DefaultExpectedShutdown
+	}
+	view := taskView{WritSnapshot: snap}
+	view.Managers = nil
+	view.Overdue = snap.Phase == sup.WritPhase_Quitting && now.Sub(snap.QuitTime) > expected
+	for _, mgr := range snap.Managers {
+		for _, child := range mgr.Children {
+			view.Children = append(view.Children, h.view(child, now))
+		}
+	}
+	return view
+}
+
+func (view taskView) Details() string {
+	details := []string{view.Phase.String()}
+	if !view.Started.IsZero() {
+		details = append(details, "up "+roundDuration(time.Since(view.Started)))
+	}
+	if view.Restarts > 0 {
+		details = append(details, fmt.Sprintf("%d restarts", view.Restarts))
+	}
+	if !view.QuitTime.IsZero() {
+		details = append(details, fmt.Sprintf("quit %s ago (%s)", roundDuration(time.Since(view.QuitTime)), view.QuitReason))
+	}
+	if view.Overdue {
+		details = append(details, "OVERDUE")
+	}
+	return strings.Join(details, ", ")
+}
+
+func (view taskView) writeText(buf *bytes.Buffer, depth int) {
+	fmt.Fprintf(buf, "%s%s (%s)\n", strings.Repeat("\t", depth), view.Name, view.Details())
+	for _, child := range view.Children {
+		child.writeText(buf, depth+1)
+	}
+}
+
+func roundDuration(d time.Duration) string {
+	return (d - d%time.Millisecond).String()
+}
+
+var page = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<title>supervision tree</title>
+<style>
+	body { font-family: monospace; }
+	ul { list-style: none; padding-left: 2em; }
+	.overdue { color: #c00; font-weight: bold; }
+</style>
+</head>
+<body>
+<ul>{{template "task" .}}</ul>
+</body>
+</html>
+{{define "task"}}<li{{if .Overdue}} class="overdue"{{end}}>{{.Name}} ({{.Details}})
+{{- if .Children}}<ul>{{range .Children}}{{template "task" .}}{{end}}</ul>{{end -}}
+</li>{{end}}`))
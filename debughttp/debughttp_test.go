@@ -0,0 +1,74 @@
+package debughttp
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"go.polydawn.net/go-sup"
+)
+
+func Test(t *testing.T) {
+	Convey("Given a tree with a task that won't quit", t, func() {
+		rootWrit := sup.NewTask()
+		stuck := make(chan struct{})
+		render := func(h *Handler, url string, accept string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest("GET", url, nil)
+			if accept != "" {
+				req.Header.Set("Accept", accept)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			return rec
+		}
+		var asText, asHTML, asJSON *httptest.ResponseRecorder
+		rootWrit.Run(func(super sup.Supervisor) {
+			mgr := sup.NewManager(super)
+			go mgr.NewTask("stuck").Run(func(sup.Supervisor) { <-stuck })
+			mgr.Cancel()
+			for sup.SnapshotWrit(rootWrit).Managers[0].Children[0].QuitTime.IsZero() {
+				time.Sleep(time.Millisecond)
+			}
+			time.Sleep(10 * time.Millisecond)
+
+			impatient := New(rootWrit)
+			impatient.ExpectedShutdown = time.Millisecond
+			asText = render(impatient, "/?format=text", "")
+			asHTML = render(impatient, "/", "text/html")
+			asJSON = render(New(rootWrit), "/", "application/json")
+
+			close(stuck)
+			mgr.Work()
+		})
+
+		Convey("Text lists the tasks and flags the overdue one", func() {
+			So(asText.Body.String(), ShouldContainSubstring, "stuck (quitting")
+			So(asText.Body.String(), ShouldContainSubstring, "OVERDUE")
+		})
+
+		Convey("HTML highlights the overdue task", func() {
+			So(asHTML.Header().Get("Content-Type"), ShouldStartWith, "text/html")
+			So(asHTML.Body.String(), ShouldContainSubstring, "<li>[root]")
+			So(asHTML.Body.String(), ShouldContainSubstring, `<li class="overdue">stuck`)
+		})
+
+		Convey("JSON is the snapshot, with verdicts", func() {
+			var view struct {
+				Name     []string
+				Children []struct {
+					Name    []string
+					Phase   string
+					Overdue bool
+				}
+			}
+			So(json.Unmarshal(asJSON.Body.Bytes(), &view), ShouldBeNil)
+			So(view.Children, ShouldHaveLength, 1)
+			So(view.Children[0].Name, ShouldResemble, []string{"stuck"})
+			So(view.Children[0].Phase, ShouldEqual, "quitting")
+			So(view.Children[0].Overdue, ShouldBeFalse) // the default expectation is more lenient.
+		})
+	})
+}
@@ -0,0 +1,147 @@
+package sup
+
+import (
+	"fmt"
+
+	"go.polydawn.net/go-sup/sluice"
+)
+
+/*
+	Describes what a manager does with a child's error when it's gathered.
+	See `ManagerOpts.ErrorPolicy`.
+
+		- Gather: hold the error until `Work` (or `Wait`), which quits all
+		  the other children and raises it.  Until then, the manager keeps
+		  accepting new tasks as if nothing happened.
+		- Ignore: drop the error.  `Work` won't raise it.
+		- Log: log the error, then drop it.  `Work` won't raise it.
+		- QuitAll: quit all the other children immediately (and stop
+		  accepting new tasks); `Work` raises the error as usual.
+
+	Errors are only gathered once any restart strategy is done with them:
+	a child that's restarted hasn't failed yet, as far as the policy
+	is concerned.
+
+	The policy only covers errors from your agents.  Errors the manager
+	raises itself -- an `ErrRestartIntensity` when it gives up on
+	restarts, or an `ErrShutdownTimeout` when it abandons a child --
+	are always raised by `Work`.
+*/
+type ErrorPolicy int
+
+const (
+	ErrorPolicy_Gather ErrorPolicy = iota
+	ErrorPolicy_Ignore
+	ErrorPolicy_Log
+	ErrorPolicy_QuitAll
+)
+
+func (policy ErrorPolicy) String() string {
+	switch policy {
+	case ErrorPolicy_Gather:
+		return "gather"
+	case ErrorPolicy_Ignore:
+		return "ignore"
+	case ErrorPolicy_Log:
+		return "log"
+	case ErrorPolicy_QuitAll:
+		return "quit all"
+	default:
+		return fmt.Sprintf("ErrorPolicy(%d)", int(policy))
+	}
+}
+
+/*
+	Hand a terminated child over to be gathered by `Work`,
+	and apply the error policy if it failed.
+
+	Must hold `mgr.mu`.
+*/
+func (mgr *manager) gather(wrt *writ) {
+	mgr.tombstones.Push(wrt)
+	if wrt.err == nil {
+		return
+	}
+	if mgr.errs != nil {
+		mgr.errs.Push(wrt.err)
+	}
+	switch mgr.errorPolicy {
+	case ErrorPolicy_Gather, ErrorPolicy_Ignore:
+		// Nothing to do now.
+	case ErrorPolicy_Log:
+		if mgr.raises(wrt) {
+			return // `Work` will log it.
+		}
+		mgr.tree.log(LogEvent{
+			Level: LogLevel_Error,
+			Kind:  LogKind_ChildFailed,
+			Name:  mgr.reportingTo.Name(),
+			Re:    wrt.name,
+			Msg:   fmt.Sprintf("manager ignoring child error: %s", wrt.err),
+			Err:   wrt.err,
+		})
+	case ErrorPolicy_QuitAll:
+		mgr.accepting = false
+		mgr.setQuitReason(QuitReason{Kind: QuitReason_SiblingFailed, Sibling: wrt.name, Err: wrt.err})
+		mgr.ctrlChan_quit.Fire()
+	default:
+		panic(fmt.Sprintf("invalid error policy %d", mgr.errorPolicy))
+	}
+}
+
+/*
+	True if `Work` should raise this child's error.
+*/
+func (mgr *manager) raises(wrt *writ) bool {
+	switch wrt.err.(type) {
+	case nil:
+		return false
+	case *ErrRestartIntensity, *ErrShutdownTimeout:
+		return true // the manager's own errors; not subject to the policy.
+	}
+	switch mgr.errorPolicy {
+	case ErrorPolicy_Ignore, ErrorPolicy_Log:
+		return false
+	default:
+		return true
+	}
+}
+
+func (mgr *manager) Errors() <-chan error {
+	mgr.errsOnce.Do(func() {
+		// Only start holding errors now that someone's going to read them.
+		errs := sluice.New()
+		mgr.mu.Lock()
+		mgr.errs = errs
+		mgr.mu.Unlock()
+		mgr.errsCh = make(chan error)
+		go pumpErrors(errs, mgr.errsCh, mgr.doneFuse.Selectable())
+	})
+	return mgr.errsCh
+}
+
+/*
+	Move errors from the sluice to the channel until the manager is done;
+	then deliver any stragglers, and close the channel.
+*/
+func pumpErrors(errs sluice.Sluice, ch chan<- error, done <-chan struct{}) {
+	defer close(ch)
+	next := errs.Next()
+	for {
+		select {
+		case rcv := <-next:
+			ch <- rcv.(error)
+			next = errs.Next()
+		case <-done:
+			for {
+				select {
+				case rcv := <-next:
+					ch <- rcv.(error)
+					next = errs.Next()
+				default:
+					return
+				}
+			}
+		}
+	}
+}
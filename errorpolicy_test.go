@@ -0,0 +1,132 @@
+package sup
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestErrorPolicy(t *testing.T) {
+	Convey("Given a long-lived manager", t, func() {
+		explo := fmt.Errorf("bang!")
+		exploder := func(Supervisor) { panic(explo) }
+		waiter := func(super Supervisor) { <-super.QuitCh() }
+
+		Convey("Errors arrive while the manager is still accepting", func() {
+			NewTask().Run(func(super Supervisor) {
+				mgr := NewManager(super, ManagerOpts.ErrorPolicy(ErrorPolicy_Ignore))
+				errs := mgr.Errors()
+				go mgr.NewTask("exploder").Run(exploder)
+				So(<-errs, ShouldNotBeNil)
+				So(mgr.(*manager).doneFuse.IsBlown(), ShouldBeFalse)
+
+				ran := make(chan struct{})
+				go mgr.NewTask("later").Run(func(Supervisor) { close(ran) })
+				<-ran
+
+				Convey("And ignored errors aren't raised by Work", func() {
+					So(mgr.Wait(), ShouldBeNil)
+					_, open := <-errs
+					So(open, ShouldBeFalse)
+				})
+			})
+		})
+
+		Convey("Errors aren't held for a stream nobody asked for", func() {
+			NewTask().Run(func(super Supervisor) {
+				mgr := NewManager(super, ManagerOpts.ErrorPolicy(ErrorPolicy_Ignore))
+				wrt := mgr.NewTask("exploder")
+				go wrt.Run(exploder)
+				<-wrt.DoneCh()
+				So(mgr.Wait(), ShouldBeNil)
+				So(mgr.(*manager).errs, ShouldBeNil)
+				_, open := <-mgr.Errors()
+				So(open, ShouldBeFalse)
+			})
+		})
+
+		Convey("Errors wait for a slow reader, even with a tombstone limit", func() {
+			NewTask().Run(func(super Supervisor) {
+				mgr := NewManager(super,
+					ManagerOpts.ErrorPolicy(ErrorPolicy_Ignore),
+					ManagerOpts.TombstoneLimit(0),
+				)
+				errs := mgr.Errors()
+				for i := 0; i < 3; i++ {
+					mgr.NewTask(fmt.Sprintf("exploder-%d", i)).Run(exploder)
+				}
+				So(mgr.Wait(), ShouldBeNil)
+				var n int
+				for range errs {
+					n++
+				}
+				So(n, ShouldEqual, 3)
+			})
+		})
+
+		Convey("The manager's own errors are raised regardless", func() {
+			NewTask().Run(func(super Supervisor) {
+				mgr := NewManager(super,
+					ManagerOpts.ErrorPolicy(ErrorPolicy_Ignore),
+					ManagerOpts.Restart(RestartStrategy_OneForOne),
+					ManagerOpts.RestartIntensity(2, time.Minute),
+				)
+				var runs int32
+				go mgr.NewTask("flaky").Run(FlakyAgent(&runs, 10, explo))
+				err := mgr.Wait()
+				So(err, ShouldNotBeNil)
+				So(err.(*ErrChildren).Failures, ShouldHaveLength, 1)
+				So(err.(*ErrChildren).Failures[0].Err, ShouldHaveSameTypeAs, &ErrRestartIntensity{})
+			})
+		})
+
+		Convey("The log policy logs errors as they're gathered", func() {
+			var mu sync.Mutex
+			var failures []LogEvent
+			cfg := TaskConfig{Logger: func(evt LogEvent) {
+				if evt.Kind == LogKind_ChildFailed {
+					mu.Lock()
+					failures = append(failures, evt)
+					mu.Unlock()
+				}
+			}}
+			NewTaskWithConfig(cfg).Run(func(super Supervisor) {
+				mgr := NewManager(super, ManagerOpts.ErrorPolicy(ErrorPolicy_Log))
+				wrt := mgr.NewTask("exploder")
+				go wrt.Run(exploder)
+				<-wrt.DoneCh()
+				So(mgr.Wait(), ShouldBeNil)
+			})
+			mu.Lock()
+			So(failures, ShouldHaveLength, 1)
+			So(failures[0].Re.Coda(), ShouldEqual, "exploder")
+			mu.Unlock()
+		})
+
+		Convey("The quit-all policy quits everyone without waiting for Work", func() {
+			NewTask().Run(func(super Supervisor) {
+				mgr := NewManager(super, ManagerOpts.ErrorPolicy(ErrorPolicy_QuitAll))
+				bystander := mgr.NewTask("bystander")
+				var reason QuitReason
+				go bystander.Run(func(super Supervisor) {
+					waiter(super)
+					reason = super.QuitReason()
+				})
+				go mgr.NewTask("exploder").Run(exploder)
+				<-bystander.DoneCh()
+				So(reason.Kind, ShouldEqual, QuitReason_SiblingFailed)
+				So(reason.Sibling.Coda(), ShouldEqual, "exploder")
+				ran := false
+				mgr.NewTask("rejected").Run(func(Supervisor) { ran = true })
+				So(ran, ShouldBeFalse)
+
+				err := mgr.Wait()
+				So(err, ShouldNotBeNil)
+				So(err.(*ErrChildren).Failures, ShouldHaveLength, 1)
+			})
+		})
+	})
+}
@@ -1,6 +1,8 @@
 package sup
 
 import (
+	"time"
+
 	"go.polydawn.net/meep"
 )
 
@@ -17,3 +19,120 @@ type ErrTaskPanic struct {
 	// (If this task had a manager, its name is one level up from this one.)
 	Task WritName
 }
+
+/*
+	Wraps an error returned by an `AgentE`.
+	Unlike `ErrTaskPanic`, no stack is captured: returning an error is
+	ordinary control flow, and the error can carry its own context.
+*/
+type ErrTaskFailed struct {
+	meep.TraitAutodescribing
+	meep.TraitCausable
+
+	// The name of the task that returned the error.
+	Task WritName
+}
+
+/*
+	Reported by a writ whose deadline passed before its agent returned.
+	(If the agent raised an error of its own, that's reported instead.)
+*/
+type ErrDeadlineExceeded struct {
+	meep.TraitAutodescribing
+
+	// The name of the task whose deadline passed.
+	Task WritName
+
+	Deadline time.Time
+}
+
+/*
+	Raised by a manager that gave up restarting its children, because they
+	failed more often than its configured restart intensity allows.
+	The cause is the child error that tipped it over the limit.
+*/
+type ErrRestartIntensity struct {
+	meep.TraitAutodescribing
+	meep.TraitCausable
+
+	// The name of the manager's task.
+	Task WritName
+
+	// The restarts within the intensity period, oldest first,
+	// followed by the failure that exceeded the limit.
+	History []RestartRecord
+}
+
+/*
+	Describes one child failure that a manager responded to with a restart.
+*/
+type RestartRecord struct {
+	Task WritName
+	Time time.Time
+	Err  error
+}
+
+/*
+	Returned by `ValidateChildSpecs` (and panicked by `NewSupervisorTree`)
+	when a child spec can't be used.
+*/
+type ErrInvalidChildSpec struct {
+	meep.TraitAutodescribing
+
+	// The position of the offending spec in the list.
+	Index int
+
+	// The name of the offending spec (which may be the problem).
+	Name string
+
+	// Explanation of what's wrong with it.
+	Problem string
+}
+
+/*
+	Gathered by a manager in place of a child that didn't return in time
+	after being told to quit.  The child's goroutine may still be running;
+	the manager simply stopped waiting for it.
+*/
+type ErrShutdownTimeout struct {
+	meep.TraitAutodescribing
+
+	// The name of the task that was abandoned.
+	Task WritName
+
+	// How long the manager waited for it after telling it to quit.
+	Waited time.Duration
+}
+
+/*
+	Returned by `Manager.Wait` when any children failed.
+	Lists every failure the manager gathered, in the order they were gathered.
+*/
+type ErrChildren struct {
+	meep.TraitAutodescribing
+
+	// The name of the manager's task.
+	Task WritName
+
+	Failures []ChildFailure
+}
+
+/*
+	Returns the error from each failure, so that `errors.Is` and
+	`errors.As` can see through to them.
+*/
+func (e *ErrChildren) Unwrap() []error {
+	errs := make([]error, len(e.Failures))
+	for i, failure := range e.Failures {
+		errs[i] = failure.Err
+	}
+	return errs
+}
+
+/*
+	One failed child, as gathered by a manager.
+*/
+type ChildFailure struct {
+	Task WritName
+	Err  error
+}
@@ -19,19 +19,23 @@ func ExampleDaemonSpawner() {
 
 	firehose := make(chan bigTask)
 	cntWorkFound := 0
-	var workFinder sup.Agent = sup.Behaviors.Looper(func(super sup.Supervisor) {
-		select {
-		case firehose <- bigTask(fmt.Sprintf("work-%02d", cntWorkFound)):
-			cntWorkFound++
-			if cntWorkFound >= 12 {
-				close(firehose)
-				panic(nil)
+	var workFinder sup.AgentE = func(super sup.Supervisor) error {
+		for {
+			select {
+			case firehose <- bigTask(fmt.Sprintf("work-%02d", cntWorkFound)):
+				cntWorkFound++
+				if cntWorkFound >= 12 {
+					close(firehose)
+					return nil
+				}
+			case <-super.QuitCh():
+				return nil
 			}
-		case <-super.QuitCh():
-			return
 		}
-	})
+	}
 
+	// This spawns one task per item, with no limit.
+	//  To run at most N at once, see `sup.Behaviors.Pool`.
 	var daemonMaster sup.Agent = func(super sup.Supervisor) {
 		mgr := sup.NewManager(super)
 		for {
@@ -45,8 +49,9 @@ func ExampleDaemonSpawner() {
 				taskName := string(todo)
 				go mgr.NewTask(taskName).Run(workerFactory(todo))
 			}
-			// do i need to 'step' periodically here?  how important is manager state maint?
-			// or check if it has any errors to raise, even if we're not done accepting?  yes that's important.
+			// No need to 'step' the manager here: it maintains itself.
+			//  To hear about child errors while still accepting, see
+			//  `sup.ManagerOpts.ErrorPolicy` and `Manager.Errors`.
 		}
 	procede:
 		fmt.Printf("daemonMaster wrapping up\n")
@@ -57,7 +62,7 @@ func ExampleDaemonSpawner() {
 	rootWrit := sup.NewTask()
 	rootWrit.Run(func(super sup.Supervisor) {
 		mgr := sup.NewManager(super)
-		go mgr.NewTask("workFinder").Run(workFinder)
+		go mgr.NewTask("workFinder").RunE(workFinder)
 		go mgr.NewTask("daemonMaster").Run(daemonMaster)
 		mgr.Work()
 	})
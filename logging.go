@@ -1,9 +1,12 @@
 package sup
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"strings"
+	"sync/atomic"
+	"time"
 )
 
 type WritName []string
@@ -22,6 +25,14 @@ func (wn WritName) Coda() string {
 	return wn[len(wn)-1]
 }
 
+func rootWritName(segments []string) WritName {
+	writName := WritName{}
+	for _, n := range segments {
+		writName = writName.New(n)
+	}
+	return writName
+}
+
 func (wn WritName) New(segment string) WritName {
 	result := make([]string, len(wn)+1)
 	copy(result, wn)
@@ -30,28 +41,172 @@ func (wn WritName) New(segment string) WritName {
 }
 
 /*
-	Called to log lifecycle events inside the supervision system.
+	A lifecycle event inside the supervision system, such as a manager
+	releasing a writ, or reaping a child.
+
+	Events are structured so they can be filtered and queried: switch on
+	the `Kind` rather than matching on `Msg`, which is for humans and
+	may change.  Fields that don't apply to an event are left zero.
+*/
+type LogEvent struct {
+	Level LogLevel
+	Kind  LogKind
+	Name  WritName // the manager (or task) reporting the event.  never nil.
+	Re    WritName // the child the event concerns, if any.
+	Msg   string
 
-	An example event might be
+	Err      error         // the error involved, if any (e.g. the child's failure).
+	Duration time.Duration // a time involved, if any (e.g. how long we've waited).
+	Phase    WritPhase     // the phase of the child, if relevant.
+}
 
-		log(mgr.FullName, "child reaped", writ.Name)
+/*
+	Levels of `LogEvent`.
 
-	which one might log as, for example:
+	Debug events are high-volume lifecycle chatter.  Warnings and errors
+	are low-volume, and generally worth printing: e.g. agents that are
+	not responding to quit signals, or children that failed.
+*/
+type LogLevel int
 
-		log.debug(evt, {"mgr":name, "regarding":re.Coda()})
-		//debug: child reaped -- mgr=root.system.subsys regarding=subproc14
+const (
+	LogLevel_Debug LogLevel = iota
+	LogLevel_Info
+	LogLevel_Warn
+	LogLevel_Error
+)
 
-	The `name` and `evt` parameters will always be nonzero; `re` is optional.
-	The `important` parameter will be true if this should *really* be printed;
-	"important" events are low-volume things and generally warnings, like
-	the warnings printed for agents that are not responding to quit signals.
+func (level LogLevel) String() string {
+	switch level {
+	case LogLevel_Debug:
+		return "debug"
+	case LogLevel_Info:
+		return "info"
+	case LogLevel_Warn:
+		return "warn"
+	case LogLevel_Error:
+		return "error"
+	default:
+		return fmt.Sprintf("LogLevel(%d)", int(level))
+	}
+}
+
+/*
+	Kinds of `LogEvent`.
+*/
+type LogKind int
+
+const (
+	LogKind_Invalid         LogKind = iota
+	LogKind_ManagerStarted          // the manager's maint actor is working.
+	LogKind_ManagerDone             // the manager has gathered all its children.
+	LogKind_WritRejected            // a writ was requested after the manager stopped accepting.
+	LogKind_WritReleased            // a writ was issued for a new child.
+	LogKind_WritTurnedIn            // a child's agent returned.
+	LogKind_ChildReaped             // the manager processed a child's return.
+	LogKind_ChildFailed             // a child returned an error, and the manager gathered it.
+	LogKind_CancellingAll           // the manager is telling all its children to quit.
+	LogKind_CancellingChild         // the manager is telling one child to quit (in ordered shutdown).
+	LogKind_StillWaiting            // the manager has quit, and children still haven't returned.
+	LogKind_ChildStack              // where a child that hasn't quit is stuck.  the stack is in `Msg`.
+	LogKind_ChildAbandoned          // the manager gave up waiting for a child.
+	LogKind_LateReturn              // an abandoned child finally returned.
+	LogKind_Restarting              // the manager is restarting children after one returned.
+	LogKind_RestartIntensity        // too many restarts: the manager is giving up.
+	LogKind_Relaunching             // a child is being relaunched.
+	LogKind_Retrying                // an agent wrapped in `Behaviors.Backoff` is retrying.
+	LogKind_Signal                  // a signal task received a signal.
+)
+
+func (kind LogKind) String() string {
+	switch kind {
+	case LogKind_ManagerStarted:
+		return "manager started"
+	case LogKind_ManagerDone:
+		return "manager done"
+	case LogKind_WritRejected:
+		return "writ rejected"
+	case LogKind_WritReleased:
+		return "writ released"
+	case LogKind_WritTurnedIn:
+		return "writ turned in"
+	case LogKind_ChildReaped:
+		return "child reaped"
+	case LogKind_ChildFailed:
+		return "child failed"
+	case LogKind_CancellingAll:
+		return "cancelling all"
+	case LogKind_CancellingChild:
+		return "cancelling child"
+	case LogKind_StillWaiting:
+		return "still waiting"
+	case LogKind_ChildStack:
+		return "child stack"
+	case LogKind_ChildAbandoned:
+		return "child abandoned"
+	case LogKind_LateReturn:
+		return "late return"
+	case LogKind_Restarting:
+		return "restarting"
+	case LogKind_RestartIntensity:
+		return "restart intensity exceeded"
+	case LogKind_Relaunching:
+		return "relaunching"
+	case LogKind_Retrying:
+		return "retrying"
+	case LogKind_Signal:
+		return "signal"
+	default:
+		return fmt.Sprintf("LogKind(%d)", int(kind))
+	}
+}
+
+/*
+	Receives lifecycle events from the supervision system.
+	See `SetLogger`.  (The `supslog` package has an adapter for `log/slog`.)
+
+	Loggers may be called from many goroutines at once, and should not block.
+*/
+type Logger func(LogEvent)
+
+/*
+	The old-style logging callback, taking the event as
+	`(name, evt, re, important)`.  Convert one to a `Logger` with its
+	`Logger` method, which passes the event's `Msg` as `evt`, and
+	treats warnings and errors as important.
 */
 type LogFn func(name WritName, evt string, re WritName, important bool)
 
-var log LogFn = func(name WritName, evt string, re WritName, _ bool) {
-	if re == nil {
-		fmt.Fprintf(os.Stderr, "mgr=%s: %q\n", name, evt)
-	} else {
-		fmt.Fprintf(os.Stderr, "mgr=%s: %q re=%s\n", name, evt, re.Coda())
+func (fn LogFn) Logger() Logger {
+	return func(evt LogEvent) {
+		fn(evt.Name, evt.Msg, evt.Re, evt.Level >= LogLevel_Warn)
+	}
+}
+
+/*
+	The default logger.  Prints every event to stderr, e.g.:
+
+		mgr=root.system.subsys: "reaped child" re=subproc14
+*/
+func DefaultLogger(evt LogEvent) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "mgr=%s: %q", evt.Name, evt.Msg)
+	if evt.Re != nil {
+		fmt.Fprintf(&buf, " re=%s", evt.Re.Coda())
 	}
+	if evt.Err != nil {
+		fmt.Fprintf(&buf, " err=%q", evt.Err)
+	}
+	buf.WriteByte('\n')
+	os.Stderr.Write(buf.Bytes())
+}
+
+var logger atomic.Value // of Logger
+
+func init() {
+	logger.Store(Logger(DefaultLogger))
+}
+
+func log(evt LogEvent) {
+	logger.Load().(Logger)(evt)
 }
@@ -1,6 +1,10 @@
 package sup
 
 import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"testing"
 
 	. "github.com/smartystreets/goconvey/convey"
@@ -33,3 +37,82 @@ func TestLogging(t *testing.T) {
 		})
 	})
 }
+
+func TestLogEvents(t *testing.T) {
+	Convey("Lifecycle events are structured", t, func() {
+		var mu sync.Mutex
+		var evts []LogEvent
+		cfg := TaskConfig{Logger: func(evt LogEvent) {
+			mu.Lock()
+			evts = append(evts, evt)
+			mu.Unlock()
+		}}
+
+		explo := fmt.Errorf("bang!")
+		NewTaskWithConfig(cfg).Run(func(super Supervisor) {
+			mgr := NewManager(super)
+			go mgr.NewTask("exploder").Run(func(Supervisor) { panic(explo) })
+			mgr.Wait()
+		})
+		var kinds []LogKind
+		var failure LogEvent
+		mu.Lock()
+		for _, evt := range evts {
+			kinds = append(kinds, evt.Kind)
+			if evt.Kind == LogKind_ChildFailed {
+				failure = evt
+			}
+		}
+		mu.Unlock()
+		So(kinds, ShouldContain, LogKind_WritReleased)
+		So(kinds, ShouldContain, LogKind_ChildReaped)
+		So(failure.Level, ShouldEqual, LogLevel_Error)
+		So(failure.Re.Coda(), ShouldEqual, "exploder")
+		So(failure.Err, ShouldNotBeNil)
+
+		Convey("Each tree logs to its own logger", func() {
+			var otherEvts int32
+			other := TaskConfig{Logger: func(LogEvent) { atomic.AddInt32(&otherEvts, 1) }}
+			NewTaskWithConfig(other).Run(func(super Supervisor) {
+				mgr := NewManager(super)
+				mgr.NewTask("child").Run(func(Supervisor) {})
+				mgr.Work()
+			})
+			So(atomic.LoadInt32(&otherEvts), ShouldBeGreaterThan, 0)
+			mu.Lock()
+			for _, evt := range evts {
+				So(evt.Re.Coda(), ShouldNotEqual, "child")
+			}
+			mu.Unlock()
+		})
+
+		Convey("Trees from contexts and signals take a config too", func() {
+			var ctxEvts, sigEvts int32
+			work := func(super Supervisor) {
+				mgr := NewManager(super)
+				mgr.NewTask("child").Run(func(Supervisor) {})
+				mgr.Work()
+			}
+			NewTaskFromContextWithConfig(context.Background(), TaskConfig{
+				Logger: func(LogEvent) { atomic.AddInt32(&ctxEvts, 1) },
+			}).Run(work)
+			NewSignalTaskWithConfig(TaskConfig{
+				Logger: func(LogEvent) { atomic.AddInt32(&sigEvts, 1) },
+			}).Run(work)
+			So(atomic.LoadInt32(&ctxEvts), ShouldBeGreaterThan, 0)
+			So(atomic.LoadInt32(&sigEvts), ShouldBeGreaterThan, 0)
+		})
+
+		Convey("Old-style log functions still work", func() {
+			var important []string
+			logger := LogFn(func(name WritName, evt string, re WritName, imp bool) {
+				if imp {
+					important = append(important, evt)
+				}
+			}).Logger()
+			logger(LogEvent{Level: LogLevel_Debug, Name: WritName{}, Msg: "chatter"})
+			logger(LogEvent{Level: LogLevel_Warn, Name: WritName{}, Msg: "uh oh"})
+			So(important, ShouldResemble, []string{"uh oh"})
+		})
+	})
+}
@@ -3,24 +3,46 @@ package sup
 import (
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"go.polydawn.net/meep"
+
 	"go.polydawn.net/go-sup/latch"
 	"go.polydawn.net/go-sup/sluice"
 )
 
 type manager struct {
 	reportingTo Supervisor // configured at start
+	tree        *tree      // configured at start.  shared config for everything under the root task.
+	owner       *writ      // configured at start.  the writ `reportingTo` belongs to, if known.
 
 	ctrlChan_winddown latch.Fuse // set at init.  fired by external event.
 	ctrlChan_quit     latch.Fuse // set at init.  fired by external event.
 	doneFuse          latch.Fuse // set at init.  fired to announce internal state change.
 
+	restartStrategy RestartStrategy // configured at start
+	restartMax      int             // configured at start.  zero for no limit.
+	restartPeriod   time.Duration   // configured at start
+	restartBackoff  Backoff         // configured at start
+	ordered         bool            // configured at start
+	shutdownTimeout time.Duration   // configured at start.  zero for no limit.
+	errorPolicy     ErrorPolicy     // configured at start
+
 	mu                 sync.Mutex      // must hold while touching wards
 	accepting          bool            // must hold `mu`.  if false, may no longer append to wards.
-	wards              map[Writ]func() // live writs -> cancelfunc
-	ctrlChan_childDone chan Writ       // writs report here when done
+	wards              map[*writ]*ward // live writs -> bookkeeping
+	nextSeq            int             // must hold `mu`.  issue order for the next ward.
+	restartQueue       []restartReq    // must hold `mu`.  terminated wards waiting to be relaunched together.
+	restartHistory     []RestartRecord // must hold `mu`.  recent restarts, for enforcing intensity.
+	cancelling         bool            // must hold `mu`.  set once we've started cancelling wards.
+	quitTime           time.Time       // must hold `mu`.  when we started cancelling wards.
+	quitReason         QuitReason      // must hold `mu`.  why we're quitting; passed on to the wards.
+	ctrlChan_childDone chan *writ      // writs report here when done
 	tombstones         sluice.Sluice   // of `Writ`s that are done and not yet externally ack'd.  no sync needed.
+	errs               sluice.Sluice   // must hold `mu` to set.  of child errors, for `Errors`.  nil until someone asks.
+	errsOnce           sync.Once       // guards starting the pump for `Errors`.
+	errsCh             chan error      // see `Errors`.
 }
 
 type (
@@ -30,7 +52,7 @@ type (
 	}
 )
 
-func newManager(reportingTo Supervisor) Manager {
+func newManager(reportingTo Supervisor, opts ...ManagerOpt) Manager {
 	mgr := &manager{
 		reportingTo: reportingTo,
 
@@ -39,16 +61,49 @@ func newManager(reportingTo Supervisor) Manager {
 		doneFuse:          latch.NewFuse(),
 
 		accepting:          true,
-		wards:              make(map[Writ]func()),
-		ctrlChan_childDone: make(chan Writ),
+		wards:              make(map[*writ]*ward),
+		ctrlChan_childDone: make(chan *writ),
 		tombstones:         sluice.New(),
 	}
+	for _, opt := range opts {
+		opt(mgr)
+	}
+	mgr.tree, _ = reportingTo.Value(treeKey{}).(*tree)
+	mgr.owner, _ = reportingTo.Value(writKey{}).(*writ)
+	mgr.owner.addManager(mgr)
 	go mgr.run()
 	return mgr
 }
 
 func (mgr *manager) NewTask(name string) Writ {
-	return mgr.releaseWrit(name)
+	return mgr.releaseWrit(name, &ward{})
+}
+
+func (mgr *manager) NewTaskWithDeadline(name string, deadline time.Time) Writ {
+	return mgr.releaseWrit(name, &ward{deadline: deadline})
+}
+
+func (mgr *manager) NewTaskWithTimeout(name string, timeout time.Duration) Writ {
+	return mgr.NewTaskWithDeadline(name, time.Now().Add(timeout))
+}
+
+func (mgr *manager) StartChild(spec ChildSpec) Writ {
+	wrt := mgr.releaseWrit(spec.Name, &ward{
+		policy:          spec.Restart,
+		shutdownTimeout: spec.ShutdownTimeout,
+	})
+	agent := spec.Agent
+	if !spec.AwaitReady {
+		agent = func(super Supervisor) {
+			super.Ready()
+			spec.Agent(super)
+		}
+	}
+	go wrt.Run(agent)
+	if mgr.ordered {
+		mgr.awaitReady(wrt.(*writ))
+	}
+	return wrt
 }
 
 /*
@@ -62,11 +117,40 @@ func (mgr *manager) NewTask(name string) Writ {
 		- Saves that error
 		- Keeps waiting
 	  - When all children are done...
-	  - Panic up the first error we gathered.  (The rest are lost.)
+	  - Panic up the first error we gathered.  (The rest are logged.)
+
+	See `Wait` for a version that returns all the errors instead.
 */
 func (mgr *manager) Work() {
-	mgr.ctrlChan_winddown.Fire()
-	var devastation error
+	if errs := mgr.gatherAll(); len(errs) > 0 {
+		panic(errs[0].Err)
+	}
+}
+
+/*
+	As per `Work`, but instead of panicking the first error, returns
+	an `ErrChildren` listing every child that failed (or nil, if none did).
+*/
+func (mgr *manager) Wait() error {
+	errs := mgr.gatherAll()
+	if len(errs) == 0 {
+		return nil
+	}
+	return meep.Meep(&ErrChildren{
+		Task:     mgr.reportingTo.Name(),
+		Failures: errs,
+	})
+}
+
+/*
+	Wind down, wait for all children, and collect their errors.
+	The first child error causes everyone else to be told to quit.
+*/
+func (mgr *manager) gatherAll() (failures []ChildFailure) {
+	mgr.Winddown()
+	// Keep the sluice read request until it's answered; dropping it
+	//  could lose a tombstone.
+	next := mgr.tombstones.Next()
 
 	// While we're in the winddown state --
 	//  Passively collecting results, and jump ourselves to quit in case of errors.
@@ -76,13 +160,20 @@ PreDoneLoop:
 		//  have a select over `<-mgr.reportingTo.QuitCh()` here as well.
 		//  but we don't really believe in that: cleanup is important.
 		select {
-		case rcv := <-mgr.tombstones.Next():
+		case rcv := <-next:
+			next = mgr.tombstones.Next()
 			writ := (rcv).(*writ)
-			if writ.err != nil {
-				msg := fmt.Sprintf("manager autoquitting because of error child error: %s", writ.err)
-				log(mgr.reportingTo.Name(), msg, writ.name, false)
-				devastation = writ.err
-				mgr.ctrlChan_quit.Fire()
+			if mgr.raises(writ) {
+				mgr.tree.log(LogEvent{
+					Level: LogLevel_Error,
+					Kind:  LogKind_ChildFailed,
+					Name:  mgr.reportingTo.Name(),
+					Re:    writ.name,
+					Msg:   fmt.Sprintf("manager autoquitting because of error child error: %s", writ.err),
+					Err:   writ.err,
+				})
+				failures = append(failures, ChildFailure{writ.name, writ.err})
+				mgr.quit(QuitReason{Kind: QuitReason_SiblingFailed, Sibling: writ.name, Err: writ.err})
 				break PreDoneLoop
 			}
 		case <-mgr.doneFuse.Selectable():
@@ -94,14 +185,15 @@ PreDoneLoop:
 	//  we can warn you about children not responding to quit reasonably quickly.
 	quitTime := time.Now()
 	tick := time.NewTicker(2 * time.Second)
+	stacksLogged := make(map[*writ]struct{})
 YUNoDoneLoop:
 	for {
 		select {
 		case <-tick.C:
 			mgr.mu.Lock()
 			var names []string
-			for ward, _ := range mgr.wards {
-				names = append(names, ward.Name().Coda())
+			for wrt := range mgr.wards {
+				names = append(names, wrt.Name().Coda())
 				if len(names) > 6 {
 					names = append(names, "...")
 					break
@@ -112,8 +204,22 @@ YUNoDoneLoop:
 				len(mgr.wards),
 				names,
 			)
+			laggards := make([]*writ, 0, len(mgr.wards))
+			for wrt := range mgr.wards {
+				if _, done := stacksLogged[wrt]; !done {
+					laggards = append(laggards, wrt)
+					stacksLogged[wrt] = struct{}{}
+				}
+			}
 			mgr.mu.Unlock()
-			log(mgr.reportingTo.Name(), msg, nil, true)
+			mgr.tree.log(LogEvent{
+				Level:    LogLevel_Warn,
+				Kind:     LogKind_StillWaiting,
+				Name:     mgr.reportingTo.Name(),
+				Msg:      msg,
+				Duration: time.Now().Sub(quitTime),
+			})
+			mgr.logStacks(laggards)
 		case <-mgr.doneFuse.Selectable():
 			break YUNoDoneLoop
 		}
@@ -121,22 +227,28 @@ YUNoDoneLoop:
 	tick.Stop()
 
 	// Now that we're fully done: range over all the child tombstones, so that
-	//  any errors can be raised upstream (or if we already have a little
-	//   bundle of joy, at least make brief mention of others in the log).
+	//  any errors can be gathered (and at least make brief mention of them
+	//   in the log).
 FinalizeLoop:
 	for {
 		select {
-		case rcv := <-mgr.tombstones.Next():
+		case rcv := <-next:
+			next = mgr.tombstones.Next()
 			writ := (rcv).(*writ)
-			if writ.err != nil {
-				if devastation != nil {
-					msg := fmt.Sprintf("manager gathered additional errors while shutting down: %s", writ.err)
-					log(mgr.reportingTo.Name(), msg, writ.name, true)
-					continue
-				}
+			if mgr.raises(writ) {
 				msg := fmt.Sprintf("manager gathered an error while shutting down: %s", writ.err)
-				log(mgr.reportingTo.Name(), msg, writ.name, true)
-				devastation = writ.err
+				if len(failures) > 0 {
+					msg = fmt.Sprintf("manager gathered additional errors while shutting down: %s", writ.err)
+				}
+				mgr.tree.log(LogEvent{
+					Level: LogLevel_Error,
+					Kind:  LogKind_ChildFailed,
+					Name:  mgr.reportingTo.Name(),
+					Re:    writ.name,
+					Msg:   msg,
+					Err:   writ.err,
+				})
+				failures = append(failures, ChildFailure{writ.name, writ.err})
 			}
 		default:
 			// no new tombstones should be coming, so the first time
@@ -144,13 +256,62 @@ FinalizeLoop:
 			break FinalizeLoop
 		}
 	}
+	return
+}
 
-	// If we collected a child error at any point, raise it now.
-	if devastation != nil {
-		panic(devastation)
+/*
+	Log where each of the given children is stuck, so you can see not
+	just who isn't quitting, but why.  Each child's stack is only worth
+	logging once; the caller keeps track of that.
+*/
+func (mgr *manager) logStacks(laggards []*writ) {
+	if len(laggards) == 0 {
+		return
+	}
+	ids := make(map[int64]struct{}, len(laggards))
+	goroutines := make(map[*writ]int64, len(laggards))
+	for _, wrt := range laggards {
+		wrt.mu.Lock()
+		id := wrt.goroutine
+		wrt.mu.Unlock()
+		if id != 0 {
+			ids[id] = struct{}{}
+			goroutines[wrt] = id
+		}
+	}
+	stacks := goroutineStacks(ids)
+	for _, wrt := range laggards {
+		stack, ok := stacks[goroutines[wrt]]
+		if !ok {
+			continue // not started yet, or returned in the meanwhile.
+		}
+		mgr.tree.log(LogEvent{
+			Level: LogLevel_Warn,
+			Kind:  LogKind_ChildStack,
+			Name:  mgr.reportingTo.Name(),
+			Re:    wrt.name,
+			Msg:   "child still hasn't quit; it's at:\n" + stack,
+			Phase: WritPhase(atomic.LoadInt32(&wrt.phase)) & ^writFlag_Used,
+		})
 	}
 }
 
 func (mgr *manager) GatherChild() <-chan sluice.T {
 	return mgr.tombstones.Next()
 }
+
+func (mgr *manager) Cancel() {
+	// Stop accepting right away, so the caller can count on that
+	//  as soon as we return, even if the maint actor is yet to wake.
+	mgr.stopAccepting()
+	mgr.quit(QuitReason{Kind: QuitReason_Cancelled})
+}
+
+func (mgr *manager) Winddown() {
+	mgr.stopAccepting()
+	mgr.ctrlChan_winddown.Fire()
+}
+
+func (mgr *manager) DoneCh() <-chan struct{} {
+	return mgr.doneFuse.Selectable()
+}
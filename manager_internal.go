@@ -1,5 +1,12 @@
 package sup
 
+import (
+	"fmt"
+	"time"
+
+	"go.polydawn.net/meep"
+)
+
 // this file contains the state machine functions for the inner workings of manager
 
 /*
@@ -10,8 +17,8 @@ package sup
 	purely internal so it can reliably handle its own blocking behavior.
 */
 func (mgr *manager) run() {
-	log(mgr.reportingTo.Name(), "working", nil, false)
-	defer log(mgr.reportingTo.Name(), "all done", nil, false)
+	mgr.tree.log(LogEvent{Level: LogLevel_Debug, Kind: LogKind_ManagerStarted, Name: mgr.reportingTo.Name(), Msg: "working"})
+	defer mgr.tree.log(LogEvent{Level: LogLevel_Debug, Kind: LogKind_ManagerDone, Name: mgr.reportingTo.Name(), Msg: "all done"})
 	stepFn := mgr.step_Accepting
 	for {
 		if stepFn == nil {
@@ -38,10 +45,16 @@ type mgr_step func() mgr_step
 	This function gathers childDone signals and waits for quit or winddown instructions.
 */
 func (mgr *manager) step_Accepting() mgr_step {
+	alarm, stopAlarm := mgr.abandonAlarm()
+	defer stopAlarm()
+
 	select {
 	case childDone := <-mgr.ctrlChan_childDone:
 		mgr.reapChild(childDone)
 		return mgr.step_Accepting
+	case <-alarm:
+		mgr.abandonOverdue()
+		return mgr.step_Accepting
 
 	case <-mgr.ctrlChan_quit.Selectable():
 		mgr.stopAccepting()
@@ -71,11 +84,16 @@ func (mgr *manager) step_Winddown() mgr_step {
 	if len(mgr.wards) == 0 {
 		return mgr.step_Terminated
 	}
+	alarm, stopAlarm := mgr.abandonAlarm()
+	defer stopAlarm()
 
 	select {
 	case childDone := <-mgr.ctrlChan_childDone:
 		mgr.reapChild(childDone)
 		return mgr.step_Winddown
+	case <-alarm:
+		mgr.abandonOverdue()
+		return mgr.step_Winddown
 
 	case <-mgr.ctrlChan_quit.Selectable():
 		mgr.cancelAll()
@@ -91,16 +109,27 @@ func (mgr *manager) step_Winddown() mgr_step {
 	well, quit.
 	There's no significant difference to this phase, other than that we no
 	long select on either the winddown or quit transitions.
+	If shutdown timeouts are configured, this is where they usually bite:
+	children that don't return in time are abandoned.  (So are all
+	children, if the whole tree is abandoned; see `NewSignalTask`.)
 */
 func (mgr *manager) step_Quitting() mgr_step {
 	if len(mgr.wards) == 0 {
 		return mgr.step_Terminated
 	}
+	alarm, stopAlarm := mgr.abandonAlarm()
+	defer stopAlarm()
 
 	select {
 	case childDone := <-mgr.ctrlChan_childDone:
 		mgr.reapChild(childDone)
 		return mgr.step_Quitting
+	case <-alarm:
+		mgr.abandonOverdue()
+		return mgr.step_Quitting
+	case <-mgr.tree.abandonCh():
+		mgr.abandonAll()
+		return mgr.step_Quitting
 	}
 }
 
@@ -111,10 +140,11 @@ func (mgr *manager) step_Quitting() mgr_step {
 */
 func (mgr *manager) step_Terminated() mgr_step {
 	// Let others see us as done.  yayy!
+	mgr.owner.removeManager(mgr)
 	mgr.doneFuse.Fire()
 	// We've finally stopped selecting.  We're done.  We're out.
-	// No other goroutines alive should have reach to this channel, so we can close it.
-	close(mgr.ctrlChan_childDone)
+	// We don't close `ctrlChan_childDone`: abandoned children may still
+	//  turn in arbitrarily late, and they watch `doneFuse` to know not to bother.
 	// It's over.  No more step functions to call.
 	return nil
 }
@@ -125,33 +155,53 @@ func (mgr *manager) step_Terminated() mgr_step {
 	Release a new writ, appending to wards -- or, if in any state other
 	than accepting, return a thunk implement writ but rejecting any work.
 
-	This is the only action that can be called from outside the maint actor.
+	This is the only action that can be called from outside the maint actor
+	(besides waiting).
 */
-func (mgr *manager) releaseWrit(name string) Writ {
+func (mgr *manager) releaseWrit(name string, wd *ward) Writ {
 	mgr.mu.Lock()
 	defer mgr.mu.Unlock()
 	// No matter what, we're responding, and it earns a name.
 	writName := mgr.reportingTo.Name().New(name)
 	// If outside of the accepting states, reject by responding with a doa writ.
 	if !mgr.accepting {
-		log(mgr.reportingTo.Name(), "manager rejected writ requisition", writName, false)
-		// Send back an unusable monad.
-		return &writ{
-			name:  writName,
-			phase: int32(WritPhase_Terminal),
-		}
+		mgr.tree.log(LogEvent{Level: LogLevel_Debug, Kind: LogKind_WritRejected, Name: mgr.reportingTo.Name(), Re: writName, Msg: "manager rejected writ requisition"})
+		mgr.tree.metrics().TaskRejected(writName)
+		// Send back an unusable monad: cancelling an unused writ
+		//  sends it straight to terminal.
+		return newWrit(writName, mgr.reportingTo).Cancel()
 	}
 	// Ok, we're doing it: make a new writ to track this upcoming task.
-	log(mgr.reportingTo.Name(), "manager releasing writ", writName, false)
-	wrt := newWrit(writName)
+	mgr.tree.log(LogEvent{Level: LogLevel_Debug, Kind: LogKind_WritReleased, Name: mgr.reportingTo.Name(), Re: writName, Msg: "manager releasing writ"})
+	wrt := mgr.issueWrit(writName, wd)
+	// Register it.
+	wd.seq = mgr.nextSeq
+	mgr.nextSeq++
+	mgr.wards[wrt] = wd
+	// Release it into the wild.
+	return wrt
+}
+
+/*
+	Make a new writ that reports home to this manager when done.
+	Registering it in wards is up to the caller.
+
+	Must hold `mgr.mu`.
+*/
+func (mgr *manager) issueWrit(writName WritName, wd *ward) *writ {
+	wrt := newWrit(writName, mgr.reportingTo)
+	if !wd.deadline.IsZero() {
+		wrt.setDeadline(wd.deadline)
+	}
 	// Assign our final report hook to call back home.
 	wrt.afterward = func() {
-		log(mgr.reportingTo.Name(), "writ turning in", writName, false)
-		mgr.ctrlChan_childDone <- wrt
+		mgr.tree.log(LogEvent{Level: LogLevel_Debug, Kind: LogKind_WritTurnedIn, Name: mgr.reportingTo.Name(), Re: writName, Msg: "writ turning in", Err: wrt.err})
+		select {
+		case mgr.ctrlChan_childDone <- wrt:
+		case <-mgr.doneFuse.Selectable():
+			// We were abandoned, and the manager is long gone.
+		}
 	}
-	// Register it.
-	mgr.wards[wrt] = wrt.quitFuse.Fire
-	// Release it into the wild.
 	return wrt
 }
 
@@ -161,19 +211,230 @@ func (mgr *manager) stopAccepting() {
 	mgr.mu.Unlock()
 }
 
-func (mgr *manager) reapChild(childDone Writ) {
+/*
+	True if either we or our parent have been told to quit.
+	Once this is true, restarts are no longer performed.
+*/
+func (mgr *manager) isQuitting() bool {
+	return mgr.ctrlChan_quit.IsBlown() || mgr.reportingTo.Quit()
+}
+
+func (mgr *manager) reapChild(childDone *writ) {
 	mgr.mu.Lock()
 	defer mgr.mu.Unlock()
-	log(mgr.reportingTo.Name(), "reaped child", childDone.Name(), false)
+	wd, ok := mgr.wards[childDone]
+	if !ok {
+		mgr.tree.log(LogEvent{Level: LogLevel_Warn, Kind: LogKind_LateReturn, Name: mgr.reportingTo.Name(), Re: childDone.Name(), Msg: "abandoned child turned in late", Err: childDone.err})
+		return
+	}
+	mgr.tree.log(LogEvent{Level: LogLevel_Debug, Kind: LogKind_ChildReaped, Name: mgr.reportingTo.Name(), Re: childDone.Name(), Msg: "reaped child", Err: childDone.err})
 	delete(mgr.wards, childDone)
-	mgr.tombstones.Push(childDone)
+	if mgr.cancelling && mgr.ordered {
+		mgr.cancelLast()
+	}
+	if mgr.maybeRestart(childDone, wd) {
+		return
+	}
+	mgr.gather(childDone)
 }
 
 func (mgr *manager) cancelAll() {
 	mgr.mu.Lock()
 	defer mgr.mu.Unlock()
-	log(mgr.reportingTo.Name(), "manager told to cancel all!", nil, false)
-	for _, cancelFn := range mgr.wards {
-		cancelFn()
+	mgr.tree.log(LogEvent{Level: LogLevel_Debug, Kind: LogKind_CancellingAll, Name: mgr.reportingTo.Name(), Msg: "manager told to cancel all!"})
+	mgr.cancelling = true
+	mgr.quitTime = time.Now()
+	// If nobody said why, it's because our parent quit.
+	mgr.setQuitReason(QuitReason{Kind: QuitReason_ParentQuit})
+	if mgr.ordered {
+		mgr.cancelLast()
+		return
+	}
+	for wrt, wd := range mgr.wards {
+		mgr.cancelWard(wrt, wd, mgr.quitReason)
+	}
+}
+
+/*
+	Record why we're quitting, and fire the quit fuse.
+	The first reason given is the one that sticks.
+*/
+func (mgr *manager) quit(reason QuitReason) {
+	mgr.mu.Lock()
+	mgr.setQuitReason(reason)
+	mgr.mu.Unlock()
+	mgr.ctrlChan_quit.Fire()
+}
+
+/*
+	Must hold `mgr.mu`.
+*/
+func (mgr *manager) setQuitReason(reason QuitReason) {
+	if mgr.quitReason.Kind == QuitReason_None {
+		mgr.quitReason = reason
+	}
+}
+
+/*
+	Tell a ward to quit, and start the clock on its shutdown timeout.
+
+	Must hold `mgr.mu`.
+*/
+func (mgr *manager) cancelWard(wrt *writ, wd *ward, reason QuitReason) {
+	if wd.cancelTime.IsZero() {
+		wd.cancelTime = time.Now()
+	}
+	wrt.quit(reason)
+}
+
+/*
+	Cancel the most recently issued live ward (if it's not already).
+	Called again each time a ward is reaped, this walks the wards
+	down in reverse order.
+
+	Must hold `mgr.mu`.
+*/
+func (mgr *manager) cancelLast() {
+	var last *writ
+	var lastWard *ward
+	for wrt, wd := range mgr.wards {
+		if lastWard == nil || wd.seq > lastWard.seq {
+			last, lastWard = wrt, wd
+		}
+	}
+	if last != nil && lastWard.cancelTime.IsZero() {
+		mgr.tree.log(LogEvent{Level: LogLevel_Debug, Kind: LogKind_CancellingChild, Name: mgr.reportingTo.Name(), Re: last.name, Msg: "manager cancelling in order"})
+		mgr.cancelWard(last, lastWard, mgr.quitReason)
+	}
+}
+
+/*
+	Returns a channel that fires when the next shutdown timeout expires
+	(or nil, if there are none pending), and a func to clean up after.
+
+	Timeouts run from when the manager told a ward to quit; the manager's
+	own timeout runs from when it started cancelling everything.
+*/
+func (mgr *manager) abandonAlarm() (<-chan time.Time, func()) {
+	mgr.mu.Lock()
+	deadline := mgr.nextAbandonDeadline()
+	mgr.mu.Unlock()
+	if deadline.IsZero() {
+		return nil, func() {}
+	}
+	timer := time.NewTimer(deadline.Sub(time.Now()))
+	return timer.C, func() { timer.Stop() }
+}
+
+/*
+	Returns the soonest time at which some ward is due to be abandoned,
+	or the zero time if none are.
+
+	Must hold `mgr.mu`.
+*/
+func (mgr *manager) nextAbandonDeadline() (soonest time.Time) {
+	for _, wd := range mgr.wards {
+		deadline := mgr.abandonDeadline(wd)
+		if !deadline.IsZero() && (soonest.IsZero() || deadline.Before(soonest)) {
+			soonest = deadline
+		}
+	}
+	return
+}
+
+/*
+	Returns the time at which the ward is due to be abandoned,
+	or the zero time if it has no timeout running.
+
+	Must hold `mgr.mu`.
+*/
+func (mgr *manager) abandonDeadline(wd *ward) (deadline time.Time) {
+	if !wd.cancelTime.IsZero() && wd.shutdownTimeout > 0 {
+		deadline = wd.cancelTime.Add(wd.shutdownTimeout)
+	}
+	if !mgr.quitTime.IsZero() && mgr.shutdownTimeout > 0 {
+		mgrDeadline := mgr.quitTime.Add(mgr.shutdownTimeout)
+		if deadline.IsZero() || mgrDeadline.Before(deadline) {
+			deadline = mgrDeadline
+		}
+	}
+	return
+}
+
+/*
+	Stop waiting for any wards that have overrun their shutdown timeout.
+	Each is gathered as an `ErrShutdownTimeout` in place of whatever it
+	may eventually have returned, and forgotten about.
+*/
+func (mgr *manager) abandonOverdue() {
+	mgr.mu.Lock()
+	defer mgr.mu.Unlock()
+	now := time.Now()
+	for wrt, wd := range mgr.wards {
+		deadline := mgr.abandonDeadline(wd)
+		if deadline.IsZero() || deadline.After(now) {
+			continue
+		}
+		mgr.abandonWard(wrt, wd, now)
+	}
+	if mgr.cancelling && mgr.ordered {
+		mgr.cancelLast()
+	}
+	if len(mgr.restartQueue) > 0 {
+		mgr.flushRestarts()
+	}
+}
+
+/*
+	Stop waiting for all wards, regardless of their shutdown timeouts.
+	Used when the whole tree is being abandoned (see `NewSignalTask`).
+*/
+func (mgr *manager) abandonAll() {
+	mgr.mu.Lock()
+	defer mgr.mu.Unlock()
+	now := time.Now()
+	for wrt, wd := range mgr.wards {
+		mgr.abandonWard(wrt, wd, now)
+	}
+	mgr.restartQueue = nil
+}
+
+/*
+	Gather an `ErrShutdownTimeout` in place of whatever the ward may
+	eventually return, and forget about it.
+
+	Must hold `mgr.mu`.
+*/
+func (mgr *manager) abandonWard(wrt *writ, wd *ward, now time.Time) {
+	waited := now.Sub(wd.cancelTime)
+	if wd.cancelTime.IsZero() {
+		waited = now.Sub(mgr.quitTime)
+	}
+	mgr.tree.log(LogEvent{
+		Level:    LogLevel_Warn,
+		Kind:     LogKind_ChildAbandoned,
+		Name:     mgr.reportingTo.Name(),
+		Re:       wrt.name,
+		Msg:      fmt.Sprintf("abandoning child that failed to quit within %s", waited),
+		Duration: waited,
+	})
+	delete(mgr.wards, wrt)
+	mgr.gather(newTombstone(wrt.name, meep.Meep(
+		&ErrShutdownTimeout{Task: wrt.name, Waited: waited},
+	)))
+}
+
+/*
+	Block until the writ's agent announces it's ready (or is done),
+	or until we're quitting.
+
+	This is called from the controller's goroutine, not the maint actor.
+*/
+func (mgr *manager) awaitReady(wrt *writ) {
+	select {
+	case <-wrt.readyFuse.Selectable():
+	case <-wrt.doneFuse.Selectable():
+	case <-mgr.ctrlChan_quit.Selectable():
+	case <-mgr.reportingTo.QuitCh():
 	}
 }
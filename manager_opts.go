@@ -0,0 +1,142 @@
+package sup
+
+import (
+	"time"
+
+	"go.polydawn.net/go-sup/sluice"
+)
+
+/*
+	Configures a `Manager` at creation time.  Pass any number of these
+	to `NewManager`; they're applied in order.
+*/
+type ManagerOpt func(*manager)
+
+/*
+	Gathering type to hang manager options off of.
+
+	Typical useage is via
+
+		sup.NewManager(super, sup.ManagerOpts.Restart(sup.RestartStrategy_OneForOne))
+*/
+type ManagerOptions struct{}
+
+var ManagerOpts ManagerOptions
+
+/*
+	Sets the strategy the manager uses when a child fails.
+
+	The default is `RestartStrategy_None`: any child error causes the
+	manager to quit all other children and raise the error.
+*/
+func (ManagerOptions) Restart(strategy RestartStrategy) ManagerOpt {
+	return func(mgr *manager) {
+		mgr.restartStrategy = strategy
+	}
+}
+
+/*
+	Limits how often the manager may restart children: if more than
+	`maxRestarts` restarts would happen within `period`, the manager gives
+	up, quits all its children, and raises an `ErrRestartIntensity`
+	(carrying the recent restart history) just as `Work` would raise any
+	other child error.
+
+	The default is no limit.  This has no effect without a restart strategy.
+*/
+func (ManagerOptions) RestartIntensity(maxRestarts int, period time.Duration) ManagerOpt {
+	return func(mgr *manager) {
+		mgr.restartMax = maxRestarts
+		mgr.restartPeriod = period
+	}
+}
+
+/*
+	Delays restarts of failed children according to the given `Backoff`.
+	The delay grows with the number of times that child has been restarted
+	-- or rather, restarted since it last ran stably (see `Backoff.ResetAfter`).
+
+	A child waiting out its delay still counts as a ward of the manager;
+	if the manager quits, the wait is cut short and the child is gathered
+	without being run again -- with the error it failed with last time.
+
+	The default is to restart immediately.
+	This has no effect without a restart strategy.
+*/
+func (ManagerOptions) RestartBackoff(backoff Backoff) ManagerOpt {
+	return func(mgr *manager) {
+		mgr.restartBackoff = backoff
+	}
+}
+
+/*
+	Makes the manager handle its children in order:
+
+		- `StartChild` waits for each child to become ready before returning,
+		  so children started later can depend on those started earlier.
+		- When quitting, children are cancelled in the reverse of the order
+		  they were issued, and each must return before the next is cancelled.
+
+	Readiness only applies to children launched via `StartChild`;
+	writs from `NewTask` are run by the caller, who can wait on
+	`Writ.ReadyCh` as they see fit.  See `ChildSpec.AwaitReady`.
+*/
+func (ManagerOptions) Ordered() ManagerOpt {
+	return func(mgr *manager) {
+		mgr.ordered = true
+	}
+}
+
+/*
+	Limits how long the manager waits for its children to return once it's
+	told them to quit.  Children still running after that are abandoned:
+	the manager stops waiting for them, gathers an `ErrShutdownTimeout`
+	for each in their place, and carries on shutting down.
+
+	Abandoned goroutines are not (and cannot be) stopped; this just keeps
+	one stuck child from hanging the whole supervision tree.
+
+	The default is to wait forever.  Individual children can have their own
+	(typically shorter) timeouts; see `ChildSpec.ShutdownTimeout`.
+*/
+func (ManagerOptions) ShutdownTimeout(timeout time.Duration) ManagerOpt {
+	return func(mgr *manager) {
+		mgr.shutdownTimeout = timeout
+	}
+}
+
+/*
+	Sets what the manager does with a child's error as soon as it's
+	gathered, without waiting for the controller to call `Work`.
+	This is for long-lived managers that keep accepting new tasks
+	indefinitely, like a daemon spawning a task per request.
+
+	The default is `ErrorPolicy_Gather`.  Whatever the policy, errors
+	are also sent to `Manager.Errors`.  Errors the manager raises itself
+	(`ErrRestartIntensity` and `ErrShutdownTimeout`) are always raised
+	by `Work`, even under `ErrorPolicy_Ignore` or `ErrorPolicy_Log`.
+*/
+func (ManagerOptions) ErrorPolicy(policy ErrorPolicy) ManagerOpt {
+	return func(mgr *manager) {
+		mgr.errorPolicy = policy
+	}
+}
+
+/*
+	Limits how many finished children the manager holds on to for
+	`GatherChild` (and `Work`) to collect.  Once `limit` are waiting,
+	each further child discards the oldest.  Children whose errors
+	`Work` would raise (see `ErrorPolicy`) are always kept; a limit of
+	zero keeps only those.
+
+	Use this in long-lived managers that accept tasks indefinitely,
+	and never call `GatherChild`, so they don't grow without bound.
+	The default is no limit.
+*/
+func (ManagerOptions) TombstoneLimit(limit int) ManagerOpt {
+	return func(mgr *manager) {
+		mgr.tombstones = sluice.NewBounded(limit, func(x sluice.T) bool {
+			return mgr.raises(x.(*writ))
+		})
+	}
+}
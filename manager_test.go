@@ -3,7 +3,9 @@ package sup
 import (
 	"fmt"
 	"sort"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -65,6 +67,27 @@ func TestManager(t *testing.T) {
 					So(mgr.(*manager).wards, ShouldHaveLength, 0)
 				})
 			})
+
+			Convey("And several exploding tasks!", func() {
+				go mgr.NewTask("e1").Run(ExplosiveAgent(fmt.Errorf("bang!")))
+				go mgr.NewTask("e2").Run(ExplosiveAgent(fmt.Errorf("boom!")))
+				go mgr.NewTask("e3").Run(ExplosiveAgent(fmt.Errorf("kaboom!")))
+
+				Convey("Manager.Wait should return all the errors", func() {
+					err := mgr.Wait()
+					So(err, ShouldHaveSameTypeAs, &ErrChildren{})
+					failures := err.(*ErrChildren).Failures
+					So(failures, ShouldHaveLength, 3)
+					var names []string
+					for _, failure := range failures {
+						So(failure.Err, ShouldHaveSameTypeAs, &ErrTaskPanic{})
+						names = append(names, failure.Task.Coda())
+					}
+					sort.Strings(names)
+					So(names, ShouldResemble, []string{"e1", "e2", "e3"})
+					So(mgr.(*manager).wards, ShouldHaveLength, 0)
+				})
+			})
 		})
 	})
 }
@@ -83,3 +106,165 @@ func ExplosiveAgent(err error) Agent {
 		panic(err)
 	}
 }
+
+func TestShutdownTimeouts(t *testing.T) {
+	Convey("Given a Manager with a child that won't quit", t, func() {
+		stuck := make(chan struct{})
+		defer close(stuck)
+		stubbornAgent := func(Supervisor) { <-stuck }
+
+		rootWrit := NewTask()
+		rootWrit.Run(func(super Supervisor) {
+			Convey("The manager's shutdown timeout abandons it", func() {
+				mgr := NewManager(super, ManagerOpts.ShutdownTimeout(20*time.Millisecond))
+				ch := make(chan string, 1)
+				go mgr.NewTask("stubborn").Run(stubbornAgent)
+				go mgr.NewTask("polite").Run(ChanWriterAgent("polite", ch))
+				<-ch
+				mgr.Cancel()
+				var raised interface{}
+				func() {
+					defer func() { raised = recover() }()
+					mgr.Work()
+				}()
+				So(raised, ShouldHaveSameTypeAs, &ErrShutdownTimeout{})
+				So(raised.(*ErrShutdownTimeout).Task.Coda(), ShouldEqual, "stubborn")
+				So(mgr.(*manager).wards, ShouldHaveLength, 0)
+			})
+
+			Convey("A child's own shutdown timeout abandons it", func() {
+				mgr := NewManager(super)
+				mgr.StartChild(ChildSpec{
+					Name:            "stubborn",
+					Agent:           stubbornAgent,
+					ShutdownTimeout: 20 * time.Millisecond,
+				})
+				mgr.Cancel()
+				So(mgr.Work, ShouldPanic)
+				So(mgr.(*manager).wards, ShouldHaveLength, 0)
+			})
+
+			Convey("A restarted sibling's timeout starts over", func() {
+				mgr := NewManager(super, ManagerOpts.Restart(RestartStrategy_OneForAll))
+				gate := make(chan struct{})
+				var siblingRuns, flakyRuns int32
+				mgr.StartChild(ChildSpec{
+					Name:            "sibling",
+					Agent:           GatedAgent(&siblingRuns, gate),
+					ShutdownTimeout: 50 * time.Millisecond,
+				})
+				mgr.StartChild(ChildSpec{
+					Name:    "flaky",
+					Agent:   FlakyAgent(&flakyRuns, 1, fmt.Errorf("bang!")),
+					Restart: RestartPolicy_Transient,
+				})
+				for mgr.(*manager).restartCount() == 0 {
+					time.Sleep(time.Millisecond)
+				}
+				time.Sleep(100 * time.Millisecond)
+				So(mgr.Snapshot().Children, ShouldHaveLength, 1)
+				So(mgr.Snapshot().Children[0].Name.Coda(), ShouldEqual, "sibling")
+				close(gate)
+				So(mgr.Wait(), ShouldBeNil)
+				So(atomic.LoadInt32(&siblingRuns), ShouldEqual, 2)
+			})
+		})
+	})
+}
+
+func TestManagerControl(t *testing.T) {
+	Convey("Given a Manager", t, func() {
+		rootWrit := NewTask()
+		rootWrit.Run(func(super Supervisor) {
+			mgr := NewManager(super)
+			ch := make(chan string)
+			go mgr.NewTask("1").Run(ChanWriterAgent("1", ch))
+
+			Convey("Cancel quits the children without quitting the parent", func() {
+				mgr.Cancel()
+				<-mgr.DoneCh()
+				So(super.Quit(), ShouldBeFalse)
+				So(mgr.(*manager).wards, ShouldHaveLength, 0)
+				So(mgr.NewTask("late").Run(ChanWriterAgent("late", ch)).Err(), ShouldBeNil)
+			})
+
+			Convey("Winddown rejects new tasks without blocking", func() {
+				mgr.Winddown()
+				var ran bool
+				mgr.NewTask("late").Run(func(Supervisor) { ran = true })
+				So(ran, ShouldBeFalse)
+				select {
+				case <-mgr.DoneCh():
+					t.Fatal("should not be done while a child is running")
+				default:
+				}
+				So(<-ch, ShouldEqual, "1")
+				<-mgr.DoneCh()
+			})
+		})
+	})
+}
+
+func TestErrorReturningAgents(t *testing.T) {
+	Convey("Given an agent that returns errors", t, func() {
+		explo := fmt.Errorf("bang!")
+
+		Convey("A returned error becomes the writ's error", func() {
+			wrt := NewTask("a").RunE(func(Supervisor) error { return explo })
+			So(wrt.Err(), ShouldHaveSameTypeAs, &ErrTaskFailed{})
+			So(wrt.Err().(*ErrTaskFailed).Cause, ShouldEqual, explo)
+			So(wrt.Err().(*ErrTaskFailed).Task.Coda(), ShouldEqual, "a")
+		})
+
+		Convey("Returning nil is a clean exit", func() {
+			wrt := NewTask().RunE(func(Supervisor) error { return nil })
+			So(wrt.Err(), ShouldBeNil)
+		})
+
+		Convey("Managers handle returned errors like panics", func() {
+			NewTask().Run(func(super Supervisor) {
+				mgr := NewManager(super)
+				go mgr.NewTask("e").RunE(func(Supervisor) error { return explo })
+				err := mgr.Wait()
+				So(err, ShouldHaveSameTypeAs, &ErrChildren{})
+				So(err.(*ErrChildren).Failures[0].Err, ShouldHaveSameTypeAs, &ErrTaskFailed{})
+			})
+		})
+	})
+}
+
+func TestTombstoneLimit(t *testing.T) {
+	Convey("Given a manager with a tombstone limit", t, func() {
+		explo := fmt.Errorf("bang!")
+		NewTask().Run(func(super Supervisor) {
+			mgr := NewManager(super, ManagerOpts.TombstoneLimit(2))
+			for i := 0; i < 5; i++ {
+				// `Run` doesn't return until the manager has taken the child
+				// back, and it reaps them in turn, so the order is fixed.
+				mgr.NewTask(fmt.Sprintf("ok-%d", i)).Run(func(Supervisor) {})
+				if i == 1 {
+					mgr.NewTask("failed").Run(func(Supervisor) { panic(explo) })
+				}
+			}
+			mgr.NewTask("sentinel").Run(func(Supervisor) {})
+			mgr.Winddown()
+			<-mgr.DoneCh()
+
+			Convey("Only the most recent successes are kept, plus errors", func() {
+				var names []string
+				next := mgr.GatherChild()
+				for {
+					select {
+					case rcv := <-next:
+						names = append(names, rcv.(Writ).Name().Coda())
+						next = mgr.GatherChild()
+						continue
+					default:
+					}
+					break
+				}
+				So(names, ShouldResemble, []string{"failed", "ok-4", "sentinel"})
+			})
+		})
+	})
+}
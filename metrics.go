@@ -0,0 +1,56 @@
+package sup
+
+import (
+	"time"
+)
+
+/*
+	Receives counts and timings of lifecycle events, for dashboards.
+	Configure it per tree with `TaskConfig.Metrics`.
+
+	Every call is labelled with the full name of the task it concerns,
+	so you can aggregate by subsystem (e.g. by the first few segments
+	of the name).  Methods may be called from many goroutines at once,
+	and should not block.
+*/
+type Metrics interface {
+	// An agent started running.  (Including restarts.)
+	TaskStarted(name WritName)
+
+	// An agent returned.  The error is as per `Writ.Err`;
+	// the duration is how long the agent ran.
+	TaskEnded(name WritName, err error, duration time.Duration)
+
+	// An agent panicked.  Reported just before the matching `TaskEnded`.
+	TaskPanicked(name WritName, err error)
+
+	// A manager relaunched a child.  `restarts` counts this one.
+	TaskRestarted(name WritName, restarts int)
+
+	// A manager rejected a request for a new task because it had
+	// already stopped accepting work (see `Manager.Winddown`).
+	TaskRejected(name WritName)
+
+	// A task returned after being told to quit; the latency is how long
+	// that took.  Reported just after the matching `TaskEnded`.
+	ShutdownLatency(name WritName, latency time.Duration)
+}
+
+/*
+	Returns the tree's metrics, or a no-op if there are none.
+*/
+func (t *tree) metrics() Metrics {
+	if t == nil || t.collector == nil {
+		return nopMetrics{}
+	}
+	return t.collector
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) TaskStarted(WritName)                     {}
+func (nopMetrics) TaskEnded(WritName, error, time.Duration) {}
+func (nopMetrics) TaskPanicked(WritName, error)             {}
+func (nopMetrics) TaskRestarted(WritName, int)              {}
+func (nopMetrics) TaskRejected(WritName)                    {}
+func (nopMetrics) ShutdownLatency(WritName, time.Duration)  {}
@@ -0,0 +1,64 @@
+package sup
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMetrics(t *testing.T) {
+	Convey("Given a tree with metrics", t, func() {
+		m := &countingMetrics{counts: make(map[string]int)}
+		rootWrit := NewTaskWithConfig(TaskConfig{Metrics: m})
+
+		Convey("Lifecycle events are counted by task", func() {
+			explo := fmt.Errorf("bang!")
+			rootWrit.Run(func(super Supervisor) {
+				mgr := NewManager(super, ManagerOpts.Restart(RestartStrategy_OneForOne))
+				var flakyRuns int32
+				go mgr.NewTask("flaky").Run(FlakyAgent(&flakyRuns, 1, explo))
+				go mgr.NewTask("slow").Run(func(super Supervisor) {
+					for atomic.LoadInt32(&flakyRuns) < 2 {
+						time.Sleep(time.Millisecond)
+					}
+					mgr.Cancel()
+					<-super.QuitCh()
+				})
+				mgr.Work()
+				mgr.NewTask("late").Run(func(Supervisor) {})
+			})
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			So(m.counts["started [root]"], ShouldEqual, 1)
+			So(m.counts["started flaky"], ShouldEqual, 2)
+			So(m.counts["panicked flaky"], ShouldEqual, 1)
+			So(m.counts["restarted flaky"], ShouldEqual, 1)
+			So(m.counts["ended flaky"], ShouldEqual, 2)
+			So(m.counts["shutdown slow"], ShouldEqual, 1)
+			So(m.counts["rejected late"], ShouldEqual, 1)
+			So(m.counts["ended [root]"], ShouldEqual, 1)
+		})
+	})
+}
+
+type countingMetrics struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (m *countingMetrics) count(what string, name WritName) {
+	m.mu.Lock()
+	m.counts[what+" "+name.String()]++
+	m.mu.Unlock()
+}
+
+func (m *countingMetrics) TaskStarted(name WritName)                         { m.count("started", name) }
+func (m *countingMetrics) TaskEnded(name WritName, _ error, _ time.Duration) { m.count("ended", name) }
+func (m *countingMetrics) TaskPanicked(name WritName, _ error)               { m.count("panicked", name) }
+func (m *countingMetrics) TaskRestarted(name WritName, _ int)                { m.count("restarted", name) }
+func (m *countingMetrics) TaskRejected(name WritName)                        { m.count("rejected", name) }
+func (m *countingMetrics) ShutdownLatency(name WritName, _ time.Duration)    { m.count("shutdown", name) }
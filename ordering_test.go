@@ -0,0 +1,63 @@
+package sup
+
+import (
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"go.polydawn.net/go-sup/phist"
+)
+
+func TestOrderedManager(t *testing.T) {
+	Convey("Writs report readiness", t, func() {
+		wrt := NewTask()
+		proceed := make(chan struct{})
+		go wrt.Run(func(super Supervisor) {
+			super.Ready()
+			<-proceed
+		})
+		<-wrt.ReadyCh()
+		select {
+		case <-wrt.DoneCh():
+			t.Fatal("should not be done yet")
+		default:
+		}
+		close(proceed)
+		So(wrt.Err(), ShouldBeNil)
+	})
+
+	Convey("Given an ordered Manager", t, func() {
+		var mu sync.Mutex
+		var events []string
+		record := func(evt string) {
+			mu.Lock()
+			events = append(events, evt)
+			mu.Unlock()
+		}
+		orderlyAgent := func(name string) Agent {
+			return func(super Supervisor) {
+				record("start " + name)
+				super.Ready()
+				<-super.QuitCh()
+				record("stop " + name)
+			}
+		}
+
+		rootWrit := NewTask()
+		rootWrit.Run(func(super Supervisor) {
+			mgr := NewManager(super, ManagerOpts.Ordered())
+			mgr.StartChild(ChildSpec{Name: "db", Agent: orderlyAgent("db"), AwaitReady: true})
+			mgr.StartChild(ChildSpec{Name: "cache", Agent: orderlyAgent("cache"), AwaitReady: true})
+			mgr.StartChild(ChildSpec{Name: "http", Agent: orderlyAgent("http"), AwaitReady: true})
+			rootWrit.Cancel()
+			mgr.Work()
+		})
+
+		Convey("Children start in order and stop in reverse", func() {
+			So(events, ShouldHaveLength, 6)
+			So(events, phist.ShouldSequence, "start db", "start cache", "start http")
+			So(events, phist.ShouldSequence, "stop http", "stop cache", "stop db")
+		})
+	})
+}
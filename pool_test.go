@@ -0,0 +1,68 @@
+package sup
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPool(t *testing.T) {
+	Convey("Given a pool of workers", t, func() {
+		source := make(chan interface{})
+		var running, maxRunning, handled int32
+		handler := func(super Supervisor, item interface{}) {
+			now := atomic.AddInt32(&running, 1)
+			for {
+				max := atomic.LoadInt32(&maxRunning)
+				if now <= max || atomic.CompareAndSwapInt32(&maxRunning, max, now) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			if item == "explode" {
+				panic(fmt.Errorf("bang!"))
+			}
+			atomic.AddInt32(&handled, 1)
+			atomic.AddInt32(&running, -1)
+		}
+
+		Convey("It handles every item, never more than n at once", func() {
+			go func() {
+				for i := 0; i < 20; i++ {
+					source <- i
+				}
+				close(source)
+			}()
+			wrt := NewTask().Run(Behaviors.Pool(3, source, handler))
+			So(wrt.Err(), ShouldBeNil)
+			So(atomic.LoadInt32(&handled), ShouldEqual, 20)
+			So(atomic.LoadInt32(&maxRunning), ShouldBeLessThanOrEqualTo, 3)
+			So(atomic.LoadInt32(&maxRunning), ShouldBeGreaterThan, 1)
+		})
+
+		Convey("A failing worker stops the pool and raises its error", func() {
+			var sent int32
+			go func() {
+				defer close(source)
+				for i := 0; i < 100; i++ {
+					item := interface{}(i)
+					if i == 2 {
+						item = "explode"
+					}
+					select {
+					case source <- item:
+						atomic.AddInt32(&sent, 1)
+					case <-time.After(100 * time.Millisecond):
+						return // nobody's listening anymore.
+					}
+				}
+			}()
+			wrt := NewTask().Run(Behaviors.Pool(1, source, handler))
+			So(wrt.Err(), ShouldHaveSameTypeAs, &ErrTaskPanic{})
+			So(atomic.LoadInt32(&sent), ShouldBeLessThan, 100)
+		})
+	})
+}
@@ -0,0 +1,70 @@
+package sup
+
+import (
+	"fmt"
+	"os"
+)
+
+/*
+	Describes why a supervisor was told to quit.  See `Supervisor.QuitReason`.
+
+	Only the first reason sticks: if a task is cancelled and then its
+	deadline passes while it's still shutting down, the reason stays
+	`QuitReason_Cancelled`.
+*/
+type QuitReason struct {
+	Kind QuitReasonKind
+
+	// For `QuitReason_SiblingFailed`: the sibling whose failure brought
+	// the manager down (or forced a group restart), and its error.
+	// The error may be nil if a permanent child returned cleanly.
+	Sibling WritName
+	Err     error
+
+	// For `QuitReason_Signal`: the signal that was received.
+	Signal os.Signal
+}
+
+type QuitReasonKind int
+
+const (
+	QuitReason_None          QuitReasonKind = iota // not told to quit (yet).
+	QuitReason_Cancelled                           // `Writ.Cancel` or `Manager.Cancel` was called.
+	QuitReason_ParentQuit                          // the parent supervisor (or context) quit.
+	QuitReason_SiblingFailed                       // a sibling under the same manager failed.
+	QuitReason_Deadline                            // the task's deadline passed.
+	QuitReason_Signal                              // the process received an OS signal.
+)
+
+func (kind QuitReasonKind) String() string {
+	switch kind {
+	case QuitReason_None:
+		return "none"
+	case QuitReason_Cancelled:
+		return "cancelled"
+	case QuitReason_ParentQuit:
+		return "parent quit"
+	case QuitReason_SiblingFailed:
+		return "sibling failed"
+	case QuitReason_Deadline:
+		return "deadline exceeded"
+	case QuitReason_Signal:
+		return "signal"
+	default:
+		return fmt.Sprintf("QuitReasonKind(%d)", int(kind))
+	}
+}
+
+func (reason QuitReason) String() string {
+	switch reason.Kind {
+	case QuitReason_SiblingFailed:
+		if reason.Err == nil {
+			return fmt.Sprintf("%s: %s", reason.Kind, reason.Sibling)
+		}
+		return fmt.Sprintf("%s: %s: %s", reason.Kind, reason.Sibling, reason.Err)
+	case QuitReason_Signal:
+		return fmt.Sprintf("%s: %s", reason.Kind, reason.Signal)
+	default:
+		return reason.Kind.String()
+	}
+}
@@ -0,0 +1,88 @@
+package sup
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestQuitReasons(t *testing.T) {
+	Convey("Supervisors report why they were told to quit", t, func() {
+		NewTask().Run(func(super Supervisor) {
+			mgr := NewManager(super)
+
+			Convey("Not quit yet", func() {
+				var reason QuitReason
+				mgr.NewTask("a").Run(func(super Supervisor) {
+					reason = super.QuitReason()
+				})
+				mgr.Work()
+				So(reason.Kind, ShouldEqual, QuitReason_None)
+			})
+
+			Convey("Cancelled", func() {
+				var reason QuitReason
+				wrt := mgr.NewTask("a")
+				wrt.Run(func(super Supervisor) {
+					wrt.Cancel()
+					reason = super.QuitReason()
+				})
+				mgr.Work()
+				So(reason.Kind, ShouldEqual, QuitReason_Cancelled)
+			})
+
+			Convey("Deadline passed", func() {
+				var reason QuitReason
+				mgr.NewTaskWithTimeout("a", time.Millisecond).Run(func(super Supervisor) {
+					<-super.QuitCh()
+					reason = super.QuitReason()
+				})
+				mgr.Wait()
+				So(reason.Kind, ShouldEqual, QuitReason_Deadline)
+			})
+
+			Convey("Sibling failed", func() {
+				explo := fmt.Errorf("bang!")
+				var reason QuitReason
+				waiter := mgr.NewTask("waiter")
+				go waiter.Run(func(super Supervisor) {
+					<-super.QuitCh()
+					reason = super.QuitReason()
+				})
+				go mgr.NewTask("exploder").Run(func(Supervisor) { panic(explo) })
+				mgr.Wait()
+				So(reason.Kind, ShouldEqual, QuitReason_SiblingFailed)
+				So(reason.Sibling.Coda(), ShouldEqual, "exploder")
+				So(reason.Err, ShouldNotBeNil)
+			})
+
+			Convey("Manager cancelled", func() {
+				var reason QuitReason
+				go mgr.NewTask("a").Run(func(super Supervisor) {
+					<-super.QuitCh()
+					reason = super.QuitReason()
+				})
+				mgr.Cancel()
+				mgr.Work()
+				So(reason.Kind, ShouldEqual, QuitReason_Cancelled)
+			})
+		})
+
+		Convey("Parent quit", func() {
+			var reason QuitReason
+			rootWrit := NewTask()
+			rootWrit.Run(func(super Supervisor) {
+				mgr := NewManager(super)
+				go mgr.NewTask("a").Run(func(super Supervisor) {
+					<-super.QuitCh()
+					reason = super.QuitReason()
+				})
+				rootWrit.Cancel()
+				mgr.Work()
+			})
+			So(reason.Kind, ShouldEqual, QuitReason_ParentQuit)
+		})
+	})
+}
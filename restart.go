@@ -0,0 +1,277 @@
+package sup
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"go.polydawn.net/meep"
+)
+
+/*
+	Strategies a `Manager` may use when one of its children fails.
+
+	The names and behaviors are lifted directly from Erlang/OTP:
+
+		- None: don't restart anything.  A child error causes the manager
+		  to quit all other children, and `Work` raises that error.
+		- OneForOne: re-run only the failed child's `Agent`.
+		- OneForAll: cancel all the failed child's siblings, wait for them
+		  to return, then re-run every one of them (in the order they were
+		  originally issued).
+		- RestForOne: like OneForAll, but only the failed child and the
+		  siblings issued *after* it are cancelled and re-run.
+
+	Restarts only happen while the manager is accepting or winding down;
+	once the manager is quitting, failed children are gathered as usual.
+
+	A restarted child runs under a fresh `Writ` (with the same name);
+	the original `Writ` still reports the terminated attempt.
+*/
+type RestartStrategy int
+
+const (
+	RestartStrategy_None RestartStrategy = iota
+	RestartStrategy_OneForOne
+	RestartStrategy_OneForAll
+	RestartStrategy_RestForOne
+)
+
+func (strategy RestartStrategy) String() string {
+	switch strategy {
+	case RestartStrategy_None:
+		return "none"
+	case RestartStrategy_OneForOne:
+		return "one-for-one"
+	case RestartStrategy_OneForAll:
+		return "one-for-all"
+	case RestartStrategy_RestForOne:
+		return "rest-for-one"
+	default:
+		return fmt.Sprintf("RestartStrategy(%d)", int(strategy))
+	}
+}
+
+/*
+	Bookkeeping for a child under a manager.
+
+	A ward outlives any individual writ: when a child is restarted,
+	the new writ inherits its predecessor's ward, so it keeps its place
+	in the issue order.
+*/
+type ward struct {
+	seq             int           // order of issue.  restarts keep their predecessor's seq.
+	policy          RestartPolicy // from the child spec, if any.
+	shutdownTimeout time.Duration // from the child spec, if any.
+	restarts        int           // number of times this child has been restarted.
+	backoffAttempt  int           // restarts since the child last ran stably; see `Backoff.ResetAfter`.
+	restartPending  bool          // set when cancelled so it can be restarted alongside a failed sibling.
+	cancelTime      time.Time     // when the manager told this child to quit.  zero if it hasn't.
+	deadline        time.Time     // the child's deadline, if it has one.  restarts don't get more time.
+}
+
+/*
+	A child that's terminated and is waiting to be run again.
+*/
+type restartReq struct {
+	writ *writ
+	ward *ward
+}
+
+/*
+	Decide whether a child that just turned in should be restarted instead
+	of gathered; if so, do the bookkeeping (and possibly the relaunch) for it.
+
+	Returns false if the child should be gathered as normal.
+	Must hold `mgr.mu`.
+*/
+func (mgr *manager) maybeRestart(childDone *writ, wd *ward) bool {
+	if wd.restartPending {
+		// This child was cancelled so it could be restarted in a group.
+		mgr.restartQueue = append(mgr.restartQueue, restartReq{childDone, wd})
+		mgr.flushRestarts()
+		return true
+	}
+	if mgr.restartStrategy == RestartStrategy_None || mgr.isQuitting() {
+		return false
+	}
+	if childDone.reason().Kind == QuitReason_Deadline {
+		return false // restarts don't get more time, so there's no point.
+	}
+	switch wd.policy {
+	case RestartPolicy_Transient:
+		if childDone.err == nil {
+			return false
+		}
+	case RestartPolicy_Permanent:
+		if childDone.err == nil && childDone.quitFuse.IsBlown() {
+			return false // it was told to quit, and did.
+		}
+	case RestartPolicy_Temporary:
+		return false
+	}
+	if !mgr.admitRestart(childDone) {
+		return true
+	}
+	msg := fmt.Sprintf("manager restarting (%s) because permanent child returned", mgr.restartStrategy)
+	if childDone.err != nil {
+		msg = fmt.Sprintf("manager restarting (%s) because of child error: %s", mgr.restartStrategy, childDone.err)
+	}
+	mgr.tree.log(LogEvent{
+		Level: LogLevel_Info,
+		Kind:  LogKind_Restarting,
+		Name:  mgr.reportingTo.Name(),
+		Re:    childDone.name,
+		Msg:   msg,
+		Err:   childDone.err,
+	})
+	mgr.restartQueue = append(mgr.restartQueue, restartReq{childDone, wd})
+	reason := QuitReason{Kind: QuitReason_SiblingFailed, Sibling: childDone.name, Err: childDone.err}
+	switch mgr.restartStrategy {
+	case RestartStrategy_OneForOne:
+		// Nothing else to cancel.
+	case RestartStrategy_OneForAll:
+		for sibling, swd := range mgr.wards {
+			swd.restartPending = true
+			mgr.cancelWard(sibling, swd, reason)
+		}
+	case RestartStrategy_RestForOne:
+		for sibling, swd := range mgr.wards {
+			if swd.seq > wd.seq {
+				swd.restartPending = true
+				mgr.cancelWard(sibling, swd, reason)
+			}
+		}
+	default:
+		panic(fmt.Sprintf("invalid restart strategy %d", mgr.restartStrategy))
+	}
+	mgr.flushRestarts()
+	return true
+}
+
+/*
+	Record a restart in the history, checking the restart intensity.
+	If the intensity is exceeded, escalate: gather an `ErrRestartIntensity`
+	in place of the child, and quit, so it's raised just like any child error.
+
+	Returns false if the restart must not happen.
+	Must hold `mgr.mu`.
+*/
+func (mgr *manager) admitRestart(childDone *writ) bool {
+	record := RestartRecord{childDone.name, time.Now(), childDone.err}
+	if mgr.restartMax <= 0 {
+		return true
+	}
+	// Forget anything that's aged out of the intensity period.
+	horizon := record.Time.Add(-mgr.restartPeriod)
+	for len(mgr.restartHistory) > 0 && mgr.restartHistory[0].Time.Before(horizon) {
+		mgr.restartHistory = mgr.restartHistory[1:]
+	}
+	if len(mgr.restartHistory) < mgr.restartMax {
+		mgr.restartHistory = append(mgr.restartHistory, record)
+		return true
+	}
+	history := make([]RestartRecord, len(mgr.restartHistory)+1)
+	copy(history, mgr.restartHistory)
+	history[len(history)-1] = record
+	err := meep.Meep(
+		&ErrRestartIntensity{Task: mgr.reportingTo.Name(), History: history},
+		meep.Cause(childDone.err),
+	)
+	mgr.tree.log(LogEvent{
+		Level:    LogLevel_Error,
+		Kind:     LogKind_RestartIntensity,
+		Name:     mgr.reportingTo.Name(),
+		Re:       childDone.name,
+		Msg:      fmt.Sprintf("manager giving up: %d restarts in %s exceeds restart intensity", len(history), mgr.restartPeriod),
+		Err:      childDone.err,
+		Duration: mgr.restartPeriod,
+	})
+	mgr.gather(newTombstone(childDone.name, err))
+	mgr.setQuitReason(QuitReason{Kind: QuitReason_SiblingFailed, Sibling: childDone.name, Err: err})
+	mgr.ctrlChan_quit.Fire()
+	return false
+}
+
+/*
+	If every child in the restart queue has turned in, relaunch them all
+	in their original issue order -- or if we've started quitting in the
+	meanwhile, gather them instead.
+
+	Must hold `mgr.mu`.
+*/
+func (mgr *manager) flushRestarts() {
+	for _, wd := range mgr.wards {
+		if wd.restartPending {
+			return // still waiting for some siblings to return.
+		}
+	}
+	queue := mgr.restartQueue
+	mgr.restartQueue = nil
+	sort.Sort(restartQueueBySeq(queue))
+	if mgr.isQuitting() {
+		for _, req := range queue {
+			mgr.gather(req.writ)
+		}
+		return
+	}
+	for _, req := range queue {
+		if req.ward.policy == RestartPolicy_Temporary {
+			// Taken down with its siblings, but never brought back.
+			mgr.gather(req.writ)
+			continue
+		}
+		req.ward.backoffAttempt = mgr.restartBackoff.next(req.ward.backoffAttempt, req.writ.ranFor())
+		delay := mgr.restartBackoff.Delay(req.ward.backoffAttempt)
+		req.ward.backoffAttempt++
+		req.ward.restartPending = false
+		req.ward.cancelTime = time.Time{} // the new run hasn't been told to quit.
+		req.ward.restarts++
+		mgr.tree.log(LogEvent{
+			Level:    LogLevel_Info,
+			Kind:     LogKind_Relaunching,
+			Name:     mgr.reportingTo.Name(),
+			Re:       req.writ.name,
+			Msg:      fmt.Sprintf("manager relaunching child after %s", delay),
+			Duration: delay,
+		})
+		wrt := mgr.issueWrit(req.writ.name, req.ward)
+		wrt.agent = req.writ.agent
+		mgr.wards[wrt] = req.ward
+		go mgr.relaunch(wrt, req.writ, req.ward.restarts, delay)
+	}
+}
+
+/*
+	Run the predecessor's agent under the writ after a delay -- or if the
+	writ is cancelled before then, turn it in without running anything,
+	reporting whatever the predecessor did (so a failure isn't forgotten).
+*/
+func (mgr *manager) relaunch(wrt *writ, prev *writ, restarts int, delay time.Duration) {
+	if !sleepUnlessQuit(delay, wrt.quitFuse.Selectable()) {
+		wrt.turnIn(prev.err)
+		return
+	}
+	mgr.tree.metrics().TaskRestarted(wrt.name, restarts)
+	wrt.RunE(wrt.agent)
+}
+
+/*
+	How long the writ's agent has been running (or ran, if it's done:
+	a manager asks soon after the writ turns in).  Zero if it never started.
+*/
+func (writ *writ) ranFor() time.Duration {
+	writ.mu.Lock()
+	started := writ.started
+	writ.mu.Unlock()
+	if started.IsZero() {
+		return 0
+	}
+	return time.Since(started)
+}
+
+type restartQueueBySeq []restartReq
+
+func (q restartQueueBySeq) Len() int           { return len(q) }
+func (q restartQueueBySeq) Less(i, j int) bool { return q[i].ward.seq < q[j].ward.seq }
+func (q restartQueueBySeq) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
@@ -0,0 +1,173 @@
+package sup
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRestarts(t *testing.T) {
+	Convey("Given a Manager with a restart strategy", t, func() {
+		rootWrit := NewTask()
+		rootWrit.Run(func(super Supervisor) {
+			explo := fmt.Errorf("bang!")
+
+			Convey("OneForOne re-runs only the failed child", func() {
+				mgr := NewManager(super, ManagerOpts.Restart(RestartStrategy_OneForOne))
+				var flakyRuns, steadyRuns int32
+				go mgr.NewTask("flaky").Run(FlakyAgent(&flakyRuns, 2, explo))
+				go mgr.NewTask("steady").Run(CountingAgent(&steadyRuns))
+				mgr.Work()
+				So(atomic.LoadInt32(&flakyRuns), ShouldEqual, 3)
+				So(atomic.LoadInt32(&steadyRuns), ShouldEqual, 1)
+				So(mgr.(*manager).wards, ShouldHaveLength, 0)
+			})
+
+			Convey("OneForAll re-runs every child", func() {
+				mgr := NewManager(super, ManagerOpts.Restart(RestartStrategy_OneForAll))
+				var flakyRuns, steadyRuns int32
+				gate := make(chan struct{})
+				go mgr.NewTask("steady").Run(GatedAgent(&steadyRuns, gate))
+				go mgr.NewTask("flaky").Run(FlakyAgent(&flakyRuns, 1, explo))
+				go func() {
+					for atomic.LoadInt32(&steadyRuns) < 2 {
+						gate <- struct{}{}
+					}
+					close(gate)
+				}()
+				mgr.Work()
+				So(atomic.LoadInt32(&flakyRuns), ShouldEqual, 2)
+				So(atomic.LoadInt32(&steadyRuns), ShouldEqual, 2)
+			})
+
+			Convey("RestForOne re-runs only children issued after the failure", func() {
+				mgr := NewManager(super, ManagerOpts.Restart(RestartStrategy_RestForOne))
+				var beforeRuns, flakyRuns, afterRuns int32
+				gate := make(chan struct{})
+				go mgr.NewTask("before").Run(CountingAgent(&beforeRuns))
+				go mgr.NewTask("flaky").Run(FlakyAgent(&flakyRuns, 1, explo))
+				go mgr.NewTask("after").Run(GatedAgent(&afterRuns, gate))
+				go func() {
+					for atomic.LoadInt32(&afterRuns) < 2 {
+						gate <- struct{}{}
+					}
+					close(gate)
+				}()
+				mgr.Work()
+				So(atomic.LoadInt32(&beforeRuns), ShouldEqual, 1)
+				So(atomic.LoadInt32(&flakyRuns), ShouldEqual, 2)
+				So(atomic.LoadInt32(&afterRuns), ShouldEqual, 2)
+			})
+
+			Convey("Restarts can be delayed by a backoff", func() {
+				mgr := NewManager(super,
+					ManagerOpts.Restart(RestartStrategy_OneForOne),
+					ManagerOpts.RestartBackoff(Backoff{Initial: 10 * time.Millisecond, Multiplier: 2}),
+				)
+				var flakyRuns int32
+				start := time.Now()
+				go mgr.NewTask("flaky").Run(FlakyAgent(&flakyRuns, 2, explo))
+				mgr.Work()
+				So(atomic.LoadInt32(&flakyRuns), ShouldEqual, 3)
+				So(time.Now().Sub(start), ShouldBeGreaterThanOrEqualTo, 30*time.Millisecond)
+			})
+
+			Convey("A child that ran stably restarts without the grown delay", func() {
+				mgr := NewManager(super,
+					ManagerOpts.Restart(RestartStrategy_OneForOne),
+					ManagerOpts.RestartBackoff(Backoff{Initial: time.Millisecond, Multiplier: 1000, ResetAfter: 5 * time.Millisecond}),
+				)
+				var flakyRuns int32
+				start := time.Now()
+				go mgr.NewTask("flaky").Run(func(super Supervisor) {
+					time.Sleep(10 * time.Millisecond)
+					FlakyAgent(&flakyRuns, 3, explo)(super)
+				})
+				mgr.Work()
+				So(atomic.LoadInt32(&flakyRuns), ShouldEqual, 4)
+				So(time.Now().Sub(start), ShouldBeLessThan, time.Second)
+			})
+
+			Convey("Quitting cuts short a pending restart, raising the failure", func() {
+				mgr := NewManager(super,
+					ManagerOpts.Restart(RestartStrategy_OneForOne),
+					ManagerOpts.RestartBackoff(Backoff{Initial: time.Hour}),
+				)
+				var flakyRuns int32
+				go mgr.NewTask("flaky").Run(FlakyAgent(&flakyRuns, 1, explo))
+				for mgr.(*manager).restartCount() == 0 {
+					time.Sleep(time.Millisecond)
+				}
+				mgr.Cancel()
+				err := mgr.Wait()
+				So(atomic.LoadInt32(&flakyRuns), ShouldEqual, 1)
+				So(err, ShouldNotBeNil)
+				So(err.(*ErrChildren).Failures, ShouldHaveLength, 1)
+				So(err.(*ErrChildren).Failures[0].Err, ShouldHaveSameTypeAs, &ErrTaskPanic{})
+			})
+
+			Convey("Exceeding the restart intensity escalates", func() {
+				mgr := NewManager(super,
+					ManagerOpts.Restart(RestartStrategy_OneForOne),
+					ManagerOpts.RestartIntensity(2, time.Minute),
+				)
+				var flakyRuns, steadyRuns int32
+				go mgr.NewTask("flaky").Run(FlakyAgent(&flakyRuns, 10, explo))
+				go mgr.NewTask("steady").Run(GatedAgent(&steadyRuns, nil))
+				var raised interface{}
+				func() {
+					defer func() { raised = recover() }()
+					mgr.Work()
+				}()
+				So(atomic.LoadInt32(&flakyRuns), ShouldEqual, 3)
+				So(raised, ShouldHaveSameTypeAs, &ErrRestartIntensity{})
+				So(raised.(*ErrRestartIntensity).History, ShouldHaveLength, 3)
+				So(mgr.(*manager).wards, ShouldHaveLength, 0)
+			})
+		})
+	})
+}
+
+// Panics with `err` the first `failures` times it's run; then returns.
+func FlakyAgent(runs *int32, failures int32, err error) Agent {
+	return func(Supervisor) {
+		if atomic.AddInt32(runs, 1) <= failures {
+			panic(err)
+		}
+	}
+}
+
+func CountingAgent(runs *int32) Agent {
+	return func(Supervisor) {
+		atomic.AddInt32(runs, 1)
+	}
+}
+
+// Counts a run, then waits until either quit or the gate closes.
+func GatedAgent(runs *int32, gate <-chan struct{}) Agent {
+	return func(supvr Supervisor) {
+		atomic.AddInt32(runs, 1)
+		for {
+			select {
+			case _, ok := <-gate:
+				if !ok {
+					return
+				}
+			case <-supvr.QuitCh():
+				return
+			}
+		}
+	}
+}
+
+func (mgr *manager) restartCount() (n int) {
+	mgr.mu.Lock()
+	defer mgr.mu.Unlock()
+	for _, wd := range mgr.wards {
+		n += wd.restarts
+	}
+	return
+}
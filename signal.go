@@ -0,0 +1,108 @@
+package sup
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+/*
+	Prepare a new root task which quits when the process receives a signal
+	(by default, SIGINT or SIGTERM).  Use it in place of `NewTask` at the
+	start of your program.
+
+	Signals escalate:
+
+		- The first signal cancels the task, so the whole tree shuts down
+		  in an orderly fashion.  Supervisors report a `QuitReason_Signal`
+		  at the root, and `QuitReason_ParentQuit` beneath it.
+		- The second signal abandons everything: every manager in the tree
+		  stops waiting for its children (as if their shutdown timeouts had
+		  all expired), so the root task can return and your program exit.
+		  If the root task still hasn't returned after `SignalExitGrace`
+		  (say, because an agent that's stuck isn't under a manager),
+		  the process exits with status 1.
+		- The third (and any later) signal dumps the live supervision tree
+		  to stderr, so you can see who's stuck.
+
+	Signal handling stops once the task is done.
+*/
+func NewSignalTask(signals ...os.Signal) Writ {
+	return NewSignalTaskWithConfig(TaskConfig{}, signals...)
+}
+
+/*
+	As per `NewSignalTask`, with configuration as per `NewTaskWithConfig`.
+*/
+func NewSignalTaskWithConfig(cfg TaskConfig, signals ...os.Signal) Writ {
+	if len(signals) == 0 {
+		signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	sigCh := make(chan os.Signal, 3)
+	signal.Notify(sigCh, signals...)
+	wrt := newSignalTask(cfg, sigCh, signalEscalation{
+		dumpTo:    os.Stderr,
+		exit:      os.Exit,
+		exitGrace: SignalExitGrace,
+	})
+	go func() {
+		<-wrt.doneFuse.Selectable()
+		signal.Stop(sigCh)
+	}()
+	return wrt
+}
+
+/*
+	How long after the second signal a signal task waits for the root
+	task to return, before exiting the process.
+*/
+const SignalExitGrace = time.Second
+
+/*
+	What a signal task does when signals keep coming.
+	(Broken out so tests don't have to exit the process.)
+*/
+type signalEscalation struct {
+	dumpTo    io.Writer      // where the third and later signals dump the tree.
+	exit      func(code int) // called if the root task is slow to return after the second signal.
+	exitGrace time.Duration  // how slow is too slow.
+}
+
+func newSignalTask(cfg TaskConfig, sigCh <-chan os.Signal, esc signalEscalation) *writ {
+	t := newTree(cfg)
+	wrt := newRootWrit(WritName{}, nil, t)
+	go t.handleSignals(wrt, sigCh, esc)
+	return wrt
+}
+
+func (t *tree) handleSignals(root *writ, sigCh <-chan os.Signal, esc signalEscalation) {
+	var count int
+	var exitAlarm <-chan time.Time // nil until the second signal.
+	for {
+		select {
+		case sig := <-sigCh:
+			count++
+			switch count {
+			case 1:
+				t.log(LogEvent{Level: LogLevel_Warn, Kind: LogKind_Signal, Name: root.name, Msg: fmt.Sprintf("received %s: quitting", sig)})
+				root.cancel(QuitReason{Kind: QuitReason_Signal, Signal: sig})
+			case 2:
+				t.log(LogEvent{Level: LogLevel_Warn, Kind: LogKind_Signal, Name: root.name, Msg: fmt.Sprintf("received %s again: abandoning all tasks", sig)})
+				t.abandonFuse.Fire()
+				exitAlarm = time.After(esc.exitGrace)
+			default:
+				t.log(LogEvent{Level: LogLevel_Warn, Kind: LogKind_Signal, Name: root.name, Msg: fmt.Sprintf("received %s again: dumping supervision tree", sig)})
+				io.WriteString(esc.dumpTo, SnapshotWrit(root).String())
+			}
+		case <-exitAlarm:
+			t.log(LogEvent{Level: LogLevel_Error, Kind: LogKind_Signal, Name: root.name, Msg: fmt.Sprintf("still not done %s after abandoning all tasks: exiting", esc.exitGrace)})
+			esc.exit(1)
+			return
+		case <-root.doneFuse.Selectable():
+			return
+		}
+	}
+}
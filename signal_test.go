@@ -0,0 +1,93 @@
+package sup
+
+import (
+	"io"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSignalTask(t *testing.T) {
+	Convey("Given a signal task", t, func() {
+		sigCh := make(chan os.Signal)
+		dumpR, dumpW := io.Pipe()
+		var dump string
+		exited := make(chan int, 1)
+		rootWrit := newSignalTask(TaskConfig{}, sigCh, signalEscalation{
+			dumpTo:    dumpW,
+			exit:      func(code int) { exited <- code },
+			exitGrace: 50 * time.Millisecond,
+		})
+
+		Convey("The first signal quits the tree", func() {
+			var rootReason, childReason QuitReason
+			rootWrit.Run(func(super Supervisor) {
+				mgr := NewManager(super)
+				go mgr.NewTask("child").Run(func(super Supervisor) {
+					<-super.QuitCh()
+					childReason = super.QuitReason()
+				})
+				sigCh <- syscall.SIGTERM
+				<-super.QuitCh()
+				rootReason = super.QuitReason()
+				mgr.Work()
+			})
+			So(rootReason.Kind, ShouldEqual, QuitReason_Signal)
+			So(rootReason.Signal, ShouldEqual, syscall.SIGTERM)
+			So(childReason.Kind, ShouldEqual, QuitReason_ParentQuit)
+		})
+
+		Convey("The second signal abandons stuck children", func() {
+			stuck := make(chan struct{})
+			defer close(stuck)
+			var err error
+			rootWrit.Run(func(super Supervisor) {
+				mgr := NewManager(super)
+				go mgr.NewTask("stuck").Run(func(Supervisor) { <-stuck })
+				sigCh <- os.Interrupt
+				<-super.QuitCh()
+				sigCh <- os.Interrupt
+				err = mgr.Wait()
+			})
+			So(err, ShouldNotBeNil)
+			So(err.(*ErrChildren).Failures[0].Err, ShouldHaveSameTypeAs, &ErrShutdownTimeout{})
+
+			Convey("And the process doesn't exit if the root task returns", func() {
+				time.Sleep(100 * time.Millisecond)
+				So(exited, ShouldHaveLength, 0)
+			})
+		})
+
+		Convey("The second signal exits if the root task is stuck", func() {
+			stuck := make(chan struct{})
+			defer close(stuck)
+			go rootWrit.Run(func(super Supervisor) {
+				super.Ready()
+				<-stuck
+			})
+			<-rootWrit.ReadyCh()
+			sigCh <- os.Interrupt
+			sigCh <- os.Interrupt
+			So(<-exited, ShouldEqual, 1)
+		})
+
+		Convey("The third signal dumps the tree", func() {
+			rootWrit.Run(func(super Supervisor) {
+				mgr := NewManager(super)
+				go mgr.NewTask("child").Run(func(super Supervisor) { <-super.QuitCh() })
+				mgr.Winddown()
+				for i := 0; i < 3; i++ {
+					sigCh <- os.Interrupt
+				}
+				buf := make([]byte, 1024)
+				n, _ := dumpR.Read(buf)
+				dump = string(buf[:n])
+				mgr.Wait()
+			})
+			So(dump, ShouldStartWith, "[root] (quitting")
+		})
+	})
+}
@@ -6,7 +6,7 @@
 	The read channels produced by a Sluice are buffered and shall eventually
 	provide one value -- no more; to read again, get another channel.
 
-	A Sluice will internally buffer without limit.
+	A Sluice will internally buffer without limit (unless made with `NewBounded`).
 	That means if the input volume is unlimited, and the consumers are
 	slower than the producers, you will eventually run out of memory!
 	If backpressure is important, a sluice is *not* the right choice;
@@ -36,23 +36,66 @@ type Sluice interface {
 func New() Sluice {
 	return &sluice{
 		serviceReqs: make(map[chan T]struct{}),
+		limit:       -1,
+	}
+}
+
+/*
+	As per `New`, but holding at most `limit` unread values that `keep`
+	rejects: when another is pushed, the oldest of them is discarded.
+	Values that `keep` accepts are never discarded (and not counted).
+
+	A nil `keep` accepts nothing, making this a plain ring of
+	the `limit` most recent unread values.
+*/
+func NewBounded(limit int, keep func(T) bool) Sluice {
+	if keep == nil {
+		keep = func(T) bool { return false }
+	}
+	return &sluice{
+		serviceReqs: make(map[chan T]struct{}),
+		limit:       limit,
+		keep:        keep,
 	}
 }
 
 type sluice struct {
 	mu          sync.Mutex
 	serviceReqs map[chan T]struct{}
-	queue       []T
+	queue       []entry      // values that are never discarded.  (all of them, if there's no limit.)
+	discardable []entry      // values that may be discarded, oldest first.  never more than `limit`.
+	nextSeq     int          // the seq for the next push.
+	limit       int          // negative for no limit.
+	keep        func(T) bool // only used if there's a limit.
+}
+
+/*
+	A queued value, and its push order -- so that values from the two
+	queues can be handed out in the order they came in.
+*/
+type entry struct {
+	seq int
+	val T
 }
 
 func (db *sluice) Push(x T) {
 	db.mu.Lock()
 	defer db.mu.Unlock()
 	req := db.pluck()
-	if req == nil {
-		db.queue = append(db.queue, x)
-	} else {
+	if req != nil {
 		req <- x
+		return
+	}
+	e := entry{db.nextSeq, x}
+	db.nextSeq++
+	if db.limit < 0 || db.keep(x) {
+		db.queue = append(db.queue, e)
+		return
+	}
+	db.discardable = append(db.discardable, e)
+	if len(db.discardable) > db.limit {
+		db.discardable[0] = entry{} // don't hold on to it.
+		db.discardable = db.discardable[1:]
 	}
 }
 
@@ -72,11 +115,16 @@ func (db *sluice) Next() <-chan T {
 	respCh := make(chan T, 1)
 	db.mu.Lock()
 	defer db.mu.Unlock()
-	if len(db.queue) > 0 {
-		var pop T
-		pop, db.queue = db.queue[0], db.queue[1:]
-		respCh <- pop
-	} else {
+	switch {
+	case len(db.queue) > 0 && (len(db.discardable) == 0 || db.queue[0].seq < db.discardable[0].seq):
+		respCh <- db.queue[0].val
+		db.queue[0] = entry{}
+		db.queue = db.queue[1:]
+	case len(db.discardable) > 0:
+		respCh <- db.discardable[0].val
+		db.discardable[0] = entry{}
+		db.discardable = db.discardable[1:]
+	default:
 		db.serviceReqs[respCh] = struct{}{}
 	}
 	return respCh
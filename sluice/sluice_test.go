@@ -49,4 +49,55 @@ func Test(t *testing.T) {
 			})
 		})
 	})
+
+	Convey("Bounded sluice can...", t, func() {
+		gondola := NewBounded(2, func(x T) bool { return x.(string)[0] == '!' })
+
+		Convey("discard the oldest values past the limit", func() {
+			gondola.Push("a")
+			gondola.Push("!b")
+			gondola.Push("c")
+			gondola.Push("d")
+			So(<-gondola.Next(), ShouldEqual, "!b")
+			So(<-gondola.Next(), ShouldEqual, "c")
+			So(<-gondola.Next(), ShouldEqual, "d")
+		})
+
+		Convey("hand out kept and discardable values in the order they came", func() {
+			gondola.Push("a")
+			gondola.Push("!b")
+			gondola.Push("c")
+			gondola.Push("!d")
+			So(<-gondola.Next(), ShouldEqual, "a")
+			So(<-gondola.Next(), ShouldEqual, "!b")
+			So(<-gondola.Next(), ShouldEqual, "c")
+			So(<-gondola.Next(), ShouldEqual, "!d")
+		})
+
+		Convey("never discard values it's told to keep", func() {
+			gondola.Push("!a")
+			gondola.Push("!b")
+			gondola.Push("!c")
+			gondola.Push("d")
+			So(<-gondola.Next(), ShouldEqual, "!a")
+			So(<-gondola.Next(), ShouldEqual, "!b")
+			So(<-gondola.Next(), ShouldEqual, "!c")
+			So(<-gondola.Next(), ShouldEqual, "d")
+		})
+
+		Convey("still hand values straight to waiting readers", func() {
+			gondola = NewBounded(0, nil)
+			req := gondola.Next()
+			gondola.Push("x")
+			gondola.Push("y")
+			So(<-req, ShouldEqual, "x")
+			var answered bool
+			select {
+			case <-gondola.Next():
+				answered = true
+			default:
+			}
+			So(answered, ShouldEqual, false)
+		})
+	})
 }
@@ -0,0 +1,134 @@
+package sup
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+/*
+	A point-in-time view of a manager and its children -- and their
+	managers, and their children, and so on down the tree.
+
+	Snapshots are plain data: they're safe to hold onto, compare,
+	and serialize (e.g. as JSON for a debug endpoint).
+*/
+type ManagerSnapshot struct {
+	Name     WritName       // the name of the task the manager works for.
+	Children []WritSnapshot // in the order they were issued.
+}
+
+/*
+	A point-in-time view of a writ.  See `ManagerSnapshot`.
+*/
+type WritSnapshot struct {
+	Name     WritName
+	Phase    WritPhase // a running writ that's been told to quit is reported as quitting.
+	Started  time.Time // when the agent started running.  zero if it hasn't yet.
+	Restarts int       // how many times the manager has restarted this child.
+
+	QuitTime        time.Time     // when the writ was told to quit.  zero if it hasn't been.
+	QuitReason      string        // why it was told to quit, as per `QuitReason.String`.
+	ShutdownTimeout time.Duration // how long it may take to quit before it's abandoned.  zero if forever.
+
+	Managers []ManagerSnapshot `json:",omitempty"` // any managers the agent has created that aren't done yet.
+}
+
+/*
+	Take a snapshot of a writ and everything under it.
+	Use this on your root task (from `NewTask`) to get the whole tree;
+	use `Manager.Snapshot` to get the part of the tree under one manager.
+*/
+func SnapshotWrit(w Writ) WritSnapshot {
+	wrt, ok := w.(*writ)
+	if !ok {
+		return WritSnapshot{Name: w.Name()}
+	}
+	return wrt.snapshot(0)
+}
+
+func (mgr *manager) Snapshot() ManagerSnapshot {
+	// Copy out the wards (paired with their writs, as in a restart queue)
+	//  so we're not holding the lock while we recurse.
+	mgr.mu.Lock()
+	children := make([]restartReq, 0, len(mgr.wards))
+	for wrt, wd := range mgr.wards {
+		wd := *wd
+		children = append(children, restartReq{wrt, &wd})
+	}
+	mgr.mu.Unlock()
+	sort.Sort(restartQueueBySeq(children))
+
+	snap := ManagerSnapshot{
+		Name:     mgr.reportingTo.Name(),
+		Children: make([]WritSnapshot, len(children)),
+	}
+	for i, c := range children {
+		snap.Children[i] = c.writ.snapshot(c.ward.restarts)
+		snap.Children[i].ShutdownTimeout = c.ward.shutdownTimeout
+		if c.ward.shutdownTimeout <= 0 {
+			snap.Children[i].ShutdownTimeout = mgr.shutdownTimeout
+		}
+	}
+	return snap
+}
+
+func (writ *writ) snapshot(restarts int) WritSnapshot {
+	writ.mu.Lock()
+	started := writ.started
+	quitTime, quitReason := writ.quitTime, writ.quitReason
+	managers := make([]*manager, len(writ.managers))
+	copy(managers, writ.managers)
+	writ.mu.Unlock()
+
+	snap := WritSnapshot{
+		Name:     writ.name,
+		Phase:    WritPhase(atomic.LoadInt32(&writ.phase)) & ^writFlag_Used,
+		Started:  started,
+		Restarts: restarts,
+		QuitTime: quitTime,
+	}
+	if snap.Phase == WritPhase_InUse && writ.quitFuse.IsBlown() {
+		// Managers fire the quit fuse without moving the phase along.
+		snap.Phase = WritPhase_Quitting
+	}
+	if quitReason.Kind != QuitReason_None {
+		snap.QuitReason = quitReason.String()
+	}
+	for _, mgr := range managers {
+		snap.Managers = append(snap.Managers, mgr.Snapshot())
+	}
+	return snap
+}
+
+/*
+	Renders the tree as indented text, one writ per line, e.g.:
+
+		[root] (in use)
+			db (in use, up 1m30s)
+			web (quitting, up 1m29s, 2 restarts)
+*/
+func (snap WritSnapshot) String() string {
+	var buf bytes.Buffer
+	snap.writeTo(&buf, 0)
+	return buf.String()
+}
+
+func (snap WritSnapshot) writeTo(buf *bytes.Buffer, depth int) {
+	details := []string{snap.Phase.String()}
+	if !snap.Started.IsZero() {
+		details = append(details, "up "+time.Since(snap.Started).Truncate(time.Millisecond).String())
+	}
+	if snap.Restarts > 0 {
+		details = append(details, fmt.Sprintf("%d restarts", snap.Restarts))
+	}
+	fmt.Fprintf(buf, "%s%s (%s)\n", strings.Repeat("\t", depth), snap.Name, strings.Join(details, ", "))
+	for _, mgr := range snap.Managers {
+		for _, child := range mgr.Children {
+			child.writeTo(buf, depth+1)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package sup
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSnapshots(t *testing.T) {
+	Convey("Given a running tree", t, func() {
+		rootWrit := NewTask()
+		var snap WritSnapshot
+		var mgrSnap ManagerSnapshot
+		rootWrit.Run(func(super Supervisor) {
+			mgr := NewManager(super, ManagerOpts.Restart(RestartStrategy_OneForOne))
+			var flakyRuns int32
+			go mgr.NewTask("flaky").Run(func(super Supervisor) {
+				if atomic.AddInt32(&flakyRuns, 1) == 1 {
+					panic(fmt.Errorf("bang!"))
+				}
+				<-super.QuitCh()
+			})
+			inner := mgr.NewTask("inner")
+			go inner.Run(func(super Supervisor) {
+				mgr := NewManager(super)
+				go mgr.NewTask("leaf").Run(func(super Supervisor) { <-super.QuitCh() })
+				mgr.Work()
+			})
+			for mgr.(*manager).restartCount() == 0 || len(SnapshotWrit(inner).Managers) == 0 {
+				time.Sleep(time.Millisecond)
+			}
+			snap = SnapshotWrit(rootWrit)
+			mgrSnap = mgr.Snapshot()
+			mgr.Cancel()
+			mgr.Work()
+		})
+
+		Convey("It lists every live writ", func() {
+			So(snap.Name.String(), ShouldEqual, "[root]")
+			So(snap.Phase, ShouldEqual, WritPhase_InUse)
+			So(snap.Started.IsZero(), ShouldBeFalse)
+			So(snap.Managers, ShouldHaveLength, 1)
+			So(snap.Managers[0].Name, ShouldResemble, mgrSnap.Name)
+			children := mgrSnap.Children
+			So(children, ShouldHaveLength, 2)
+			So(children[0].Name.Coda(), ShouldEqual, "flaky")
+			So(children[0].Restarts, ShouldEqual, 1)
+			So(children[1].Name.Coda(), ShouldEqual, "inner")
+			So(children[1].Managers[0].Children[0].Name.Coda(), ShouldEqual, "leaf")
+		})
+
+		Convey("It serializes", func() {
+			bs, err := json.Marshal(snap)
+			So(err, ShouldBeNil)
+			So(string(bs), ShouldContainSubstring, `"Phase":"in use"`)
+			So(snap.String(), ShouldContainSubstring, "\t\tinner.leaf (")
+		})
+	})
+}
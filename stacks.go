@@ -0,0 +1,63 @@
+package sup
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+)
+
+/*
+	Returns the ID of the calling goroutine, as it appears in stack traces;
+	or zero if it can't be determined.
+
+	Go doesn't want you to have this, and for good reason: don't use it
+	for anything but finding the goroutine again in a stack dump.
+*/
+func currentGoroutineID() int64 {
+	var buf [64]byte
+	header := buf[:runtime.Stack(buf[:], false)]
+	return parseGoroutineID(header)
+}
+
+/*
+	Parses the ID out of a goroutine header line: "goroutine 123 [running]:".
+*/
+func parseGoroutineID(header []byte) int64 {
+	header = bytes.TrimPrefix(header, []byte("goroutine "))
+	if i := bytes.IndexByte(header, ' '); i >= 0 {
+		header = header[:i]
+	}
+	id, err := strconv.ParseInt(string(header), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return id
+}
+
+/*
+	Capture the stacks of all goroutines, and return the ones with the
+	requested IDs.  Goroutines that have already exited are missing from
+	the result.
+*/
+func goroutineStacks(ids map[int64]struct{}) map[int64]string {
+	buf := make([]byte, 1<<16)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		if len(buf) >= 1<<26 {
+			break // 64MB of stacks: good enough.  the rest is truncated.
+		}
+		buf = make([]byte, len(buf)*2)
+	}
+	stacks := make(map[int64]string, len(ids))
+	for _, stack := range bytes.Split(buf, []byte("\n\n")) {
+		id := parseGoroutineID(stack)
+		if _, ok := ids[id]; ok {
+			stacks[id] = string(stack)
+		}
+	}
+	return stacks
+}
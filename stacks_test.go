@@ -0,0 +1,38 @@
+package sup
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestGoroutineStacks(t *testing.T) {
+	Convey("Goroutine stacks can be found by ID", t, func() {
+		So(currentGoroutineID(), ShouldBeGreaterThan, 0)
+		So(parseGoroutineID([]byte("goroutine 42 [chan receive]:\n...")), ShouldEqual, 42)
+		So(parseGoroutineID([]byte("garbage")), ShouldEqual, 0)
+
+		Convey("A stuck task's stack shows where it's stuck", func() {
+			stuck := make(chan struct{})
+			wrt := NewTask()
+			go wrt.Run(func(Supervisor) { stuckAgent(stuck) })
+			var id int64
+			for id == 0 {
+				time.Sleep(time.Millisecond)
+				wrt.(*writ).mu.Lock()
+				id = wrt.(*writ).goroutine
+				wrt.(*writ).mu.Unlock()
+			}
+			time.Sleep(10 * time.Millisecond) // let it get to the blocking part.
+			stacks := goroutineStacks(map[int64]struct{}{id: {}})
+			close(stuck)
+			So(stacks, ShouldHaveLength, 1)
+			So(stacks[id], ShouldContainSubstring, "stuckAgent")
+		})
+	})
+}
+
+func stuckAgent(stuck <-chan struct{}) {
+	<-stuck
+}
@@ -0,0 +1,62 @@
+/*
+	`supslog` adapts go-sup's lifecycle events to `log/slog`.
+
+		sup.SetLogger(supslog.Logger(slog.Default()))
+
+	Each event becomes a record at the matching level, with the event's
+	`Msg` as the message, and its other fields as attributes:
+	"kind", "mgr", "re", "err", "duration", and "phase" (the last four
+	only when set).
+*/
+package supslog
+
+import (
+	"context"
+	"log/slog"
+
+	"go.polydawn.net/go-sup"
+)
+
+func Logger(l *slog.Logger) sup.Logger {
+	return func(evt sup.LogEvent) {
+		level := Level(evt.Level)
+		ctx := context.Background()
+		if !l.Enabled(ctx, level) {
+			return
+		}
+		attrs := make([]slog.Attr, 0, 6)
+		attrs = append(attrs,
+			slog.String("kind", evt.Kind.String()),
+			slog.String("mgr", evt.Name.String()),
+		)
+		if evt.Re != nil {
+			attrs = append(attrs, slog.String("re", evt.Re.String()))
+		}
+		if evt.Err != nil {
+			attrs = append(attrs, slog.String("err", evt.Err.Error()))
+		}
+		if evt.Duration != 0 {
+			attrs = append(attrs, slog.Duration("duration", evt.Duration))
+		}
+		if evt.Phase != sup.WritPhase_Invalid {
+			attrs = append(attrs, slog.String("phase", evt.Phase.String()))
+		}
+		l.LogAttrs(ctx, level, evt.Msg, attrs...)
+	}
+}
+
+/*
+	Maps go-sup's log levels onto slog's.
+*/
+func Level(level sup.LogLevel) slog.Level {
+	switch level {
+	case sup.LogLevel_Debug:
+		return slog.LevelDebug
+	case sup.LogLevel_Info:
+		return slog.LevelInfo
+	case sup.LogLevel_Warn:
+		return slog.LevelWarn
+	default:
+		return slog.LevelError
+	}
+}
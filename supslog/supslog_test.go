@@ -0,0 +1,48 @@
+package supslog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"go.polydawn.net/go-sup"
+)
+
+func Test(t *testing.T) {
+	Convey("Events become slog records", t, func() {
+		var buf bytes.Buffer
+		l := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
+		log := Logger(l)
+
+		log(sup.LogEvent{
+			Level:    sup.LogLevel_Warn,
+			Kind:     sup.LogKind_ChildAbandoned,
+			Name:     sup.WritName{"app"},
+			Re:       sup.WritName{"app", "db"},
+			Msg:      "abandoning child",
+			Err:      fmt.Errorf("stuck"),
+			Duration: time.Second,
+		})
+		var rec map[string]interface{}
+		So(json.Unmarshal(buf.Bytes(), &rec), ShouldBeNil)
+		So(rec["level"], ShouldEqual, "WARN")
+		So(rec["msg"], ShouldEqual, "abandoning child")
+		So(rec["kind"], ShouldEqual, "child abandoned")
+		So(rec["mgr"], ShouldEqual, "app")
+		So(rec["re"], ShouldEqual, "app.db")
+		So(rec["err"], ShouldEqual, "stuck")
+		So(rec["duration"], ShouldEqual, float64(time.Second))
+		So(rec, ShouldNotContainKey, "phase")
+
+		Convey("Filtered levels are dropped", func() {
+			buf.Reset()
+			log(sup.LogEvent{Level: sup.LogLevel_Debug, Kind: sup.LogKind_ChildReaped, Name: sup.WritName{}})
+			So(buf.Len(), ShouldEqual, 0)
+		})
+	})
+}
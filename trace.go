@@ -0,0 +1,83 @@
+package sup
+
+import (
+	"context"
+)
+
+/*
+	Opens tracing spans for tasks.  Configure it per tree with
+	`TaskConfig.Tracer`.
+
+	This is shaped so that an OpenTelemetry tracer can be adapted to it in
+	a few lines, without go-sup itself depending on OpenTelemetry:
+
+		func (t otelTracer) StartSpan(ctx context.Context, name sup.WritName) (context.Context, sup.Span) {
+			ctx, span := t.Tracer.Start(ctx, name.String())
+			return ctx, otelSpan{span}
+		}
+
+	The context passed to `StartSpan` is the parent task's supervisor --
+	the one whose manager launched this task -- so the parent's span can be
+	found in it the usual way.  The context returned is consulted for values
+	by the task's own supervisor, so the task's children are parented to
+	its span in turn, and so is anything else the agent passes its
+	supervisor to.
+*/
+type Tracer interface {
+	StartSpan(ctx context.Context, name WritName) (context.Context, Span)
+}
+
+/*
+	A span covering one run of an agent.  Go-sup records the quit reason
+	(as a "quit" event, when the task is told to quit), any panic (as a
+	"panic" event), and the task's error, if any, then ends it.
+
+	Spans may be called from many goroutines at once.
+*/
+type Span interface {
+	AddEvent(name string, attributes map[string]string)
+	RecordError(err error)
+	End()
+}
+
+/*
+	Open a span for the writ's run, if the tree has a tracer.
+	Called from `Run` before the agent starts.
+*/
+func (writ *writ) startSpan() {
+	parent := writ.svr.(*supervisor).parent
+	if writ.tree == nil || writ.tree.tracer == nil || parent == nil {
+		return
+	}
+	ctx, span := writ.tree.tracer.StartSpan(parent, writ.name)
+	writ.mu.Lock()
+	writ.span, writ.spanCtx = span, ctx
+	reason := writ.quitReason
+	writ.mu.Unlock()
+	if reason.Kind != QuitReason_None {
+		// Told to quit before we even got started.
+		span.AddEvent("quit", map[string]string{"reason": reason.String()})
+	}
+}
+
+/*
+	Record how the writ's run ended, and close its span (if any).
+	Called from `Run` after the agent returns.
+*/
+func (writ *writ) endSpan(panicked bool) {
+	// Take the span, so `quit` can't add events to it once it's ended.
+	writ.mu.Lock()
+	span := writ.span
+	writ.span = nil
+	writ.mu.Unlock()
+	if span == nil {
+		return
+	}
+	if panicked {
+		span.AddEvent("panic", map[string]string{"error": writ.err.Error()})
+	}
+	if writ.err != nil {
+		span.RecordError(writ.err)
+	}
+	span.End()
+}
@@ -0,0 +1,120 @@
+package sup
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTracing(t *testing.T) {
+	Convey("Given a tree with a tracer", t, func() {
+		tracer := &recordingTracer{}
+		rootWrit := NewTaskWithConfig(TaskConfig{Tracer: tracer})
+
+		Convey("Each run gets a span, parented to its manager's task", func() {
+			explo := fmt.Errorf("bang!")
+			rootWrit.Run(func(super Supervisor) {
+				mgr := NewManager(super)
+				go mgr.NewTask("exploder").Run(func(Supervisor) { panic(explo) })
+				waiter := mgr.NewTask("waiter")
+				go waiter.Run(func(super Supervisor) {
+					mgr := NewManager(super)
+					mgr.NewTask("leaf").Run(func(Supervisor) {})
+					mgr.Work()
+					<-super.QuitCh()
+				})
+				mgr.Wait()
+			})
+
+			spans := tracer.byName()
+			So(spans, ShouldHaveLength, 4)
+			So(spans["[root]"].parent, ShouldBeNil)
+			So(spans["exploder"].parent, ShouldEqual, spans["[root]"])
+			So(spans["waiter"].parent, ShouldEqual, spans["[root]"])
+			So(spans["waiter.leaf"].parent, ShouldEqual, spans["waiter"])
+			for _, span := range spans {
+				So(span.ended, ShouldBeTrue)
+			}
+			So(spans["exploder"].events, ShouldContain, "panic")
+			So(spans["exploder"].err, ShouldNotBeNil)
+			So(spans["waiter"].events, ShouldContain, "quit: sibling failed: exploder: "+spans["exploder"].err.Error())
+			So(spans["waiter"].err, ShouldBeNil)
+		})
+
+		Convey("Quitting after the run is over doesn't touch the ended span", func() {
+			rootWrit.Run(func(Supervisor) {})
+			rootWrit.Cancel()
+			span := tracer.byName()["[root]"]
+			So(span.ended, ShouldBeTrue)
+			So(span.events, ShouldHaveLength, 0)
+			So(span.lateCalls, ShouldEqual, 0)
+		})
+	})
+}
+
+type recordingTracer struct {
+	mu    sync.Mutex
+	spans []*recordingSpan
+}
+
+type recordingSpanKey struct{}
+
+func (t *recordingTracer) StartSpan(ctx context.Context, name WritName) (context.Context, Span) {
+	parent, _ := ctx.Value(recordingSpanKey{}).(*recordingSpan)
+	span := &recordingSpan{name: name.String(), parent: parent}
+	t.mu.Lock()
+	t.spans = append(t.spans, span)
+	t.mu.Unlock()
+	return context.WithValue(ctx, recordingSpanKey{}, span), span
+}
+
+func (t *recordingTracer) byName() map[string]*recordingSpan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	m := make(map[string]*recordingSpan)
+	for _, span := range t.spans {
+		m[span.name] = span
+	}
+	return m
+}
+
+type recordingSpan struct {
+	name   string
+	parent *recordingSpan
+
+	mu        sync.Mutex
+	events    []string
+	err       error
+	ended     bool
+	lateCalls int // calls made after `End`, which break the contract.
+}
+
+func (s *recordingSpan) AddEvent(name string, attributes map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		s.lateCalls++
+	}
+	if reason, ok := attributes["reason"]; ok {
+		name += ": " + reason
+	}
+	s.events = append(s.events, name)
+}
+
+func (s *recordingSpan) RecordError(err error) {
+	s.mu.Lock()
+	if s.ended {
+		s.lateCalls++
+	}
+	s.err = err
+	s.mu.Unlock()
+}
+
+func (s *recordingSpan) End() {
+	s.mu.Lock()
+	s.ended = true
+	s.mu.Unlock()
+}
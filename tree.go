@@ -0,0 +1,91 @@
+package sup
+
+import (
+	"context"
+
+	"go.polydawn.net/go-sup/latch"
+)
+
+/*
+	Configuration for a root task, shared by every manager and writ
+	under it.  See `NewTaskWithConfig` (and `NewTaskFromContextWithConfig`
+	and `NewSignalTaskWithConfig`).
+*/
+type TaskConfig struct {
+	// Receives lifecycle events from everything in the tree.
+	// Nil means the package default (see `SetLogger`).
+	Logger Logger
+
+	// Receives counts and timings from everything in the tree.
+	// Nil means none are collected.
+	Metrics Metrics
+
+	// Opens a tracing span for each run of each task in the tree.
+	// Nil means no tracing.
+	Tracer Tracer
+}
+
+/*
+	State shared by everything under one root task: its configuration,
+	and the means for a signal to reach every manager.
+	It rides down the tree as a context value (see `treeOf`).
+
+	A nil tree is valid: it logs to the package default logger,
+	and is never abandoned.  (Managers reporting to some other
+	implementation of `Supervisor` may not find a tree.)
+*/
+type tree struct {
+	logger      Logger     // nil for the package default.
+	collector   Metrics    // nil for none.  see `metrics()`.
+	tracer      Tracer     // nil for none.
+	abandonFuse latch.Fuse // fired on the second signal to a signal task.
+}
+
+type treeKey struct{}
+
+func newTree(cfg TaskConfig) *tree {
+	return &tree{
+		logger:      cfg.Logger,
+		collector:   cfg.Metrics,
+		tracer:      cfg.Tracer,
+		abandonFuse: latch.NewFuse(),
+	}
+}
+
+/*
+	Returns the tree the context belongs to, or nil.
+*/
+func treeOf(ctx context.Context) *tree {
+	t, _ := ctx.Value(treeKey{}).(*tree)
+	return t
+}
+
+/*
+	Create a writ at the root of a tree.  The parent context, if not nil,
+	is as per `newWrit`.
+*/
+func newRootWrit(name WritName, parent context.Context, t *tree) *writ {
+	if parent == nil {
+		parent = context.Background()
+	}
+	return newWrit(name, context.WithValue(parent, treeKey{}, t))
+}
+
+func (t *tree) log(evt LogEvent) {
+	if t == nil || t.logger == nil {
+		log(evt)
+		return
+	}
+	t.logger(evt)
+}
+
+/*
+	Returns a channel that closes if the tree is abandoned;
+	or nil (blocking forever) if there's no tree.
+*/
+func (t *tree) abandonCh() <-chan struct{} {
+	if t == nil {
+		return nil
+	}
+	return t.abandonFuse.Selectable()
+}
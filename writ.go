@@ -1,8 +1,11 @@
 package sup
 
 import (
+	"context"
 	"fmt"
+	"sync"
 	"sync/atomic"
+	"time"
 
 	"go.polydawn.net/meep"
 
@@ -13,10 +16,25 @@ type writ struct {
 	name      WritName
 	phase     int32
 	quitFuse  latch.Fuse // fire this to move to quitting
+	readyFuse latch.Fuse // the agent fires this (via the supervisor) when it's up and running
 	doneFuse  latch.Fuse // we'll fire this when moving to done
 	svr       Supervisor
+	tree      *tree // from the parent context.  may be nil.
+	agent     AgentE // set when `Run`; kept so a manager can restart it.
 	afterward func()
 	err       error
+
+	deadline time.Time // zero if none.  set before the writ is released, then constant.
+
+	mu            sync.Mutex      // must hold while touching the following fields
+	deadlineTimer *time.Timer     // nil if no deadline.  the timer may fire before it's even assigned.
+	quitReason    QuitReason      // set (once) just before quitFuse is fired.
+	quitTime      time.Time       // set along with quitReason.
+	started       time.Time       // when the agent started running.  zero if it hasn't.
+	goroutine     int64           // the ID of the goroutine running the agent, for finding its stack.
+	managers      []*manager      // live managers created by the agent, for snapshots.
+	span          Span            // the tracing span for this run, if any.
+	spanCtx       context.Context // carries the span, if any.  consulted by the supervisor for values.
 }
 
 /*
@@ -54,23 +72,82 @@ const (
 	writFlag_Used WritPhase = 1 << 8
 )
 
-func newWrit(name WritName) *writ {
+func (phase WritPhase) String() string {
+	switch phase & ^writFlag_Used {
+	case WritPhase_Issued:
+		return "issued"
+	case WritPhase_InUse:
+		return "in use"
+	case WritPhase_Quitting:
+		return "quitting"
+	case WritPhase_Terminal:
+		return "terminal"
+	default:
+		return fmt.Sprintf("WritPhase(%d)", int32(phase))
+	}
+}
+
+/*
+	Phases serialize as their names (e.g. "in use"), so snapshots are
+	readable when rendered as JSON.
+*/
+func (phase WritPhase) MarshalText() ([]byte, error) {
+	return []byte(phase.String()), nil
+}
+
+/*
+	Create a new writ.  The parent context, if not nil, is where the
+	writ's supervisor will look for values and deadlines.
+*/
+func newWrit(name WritName, parent context.Context) *writ {
 	quitFuse := latch.NewFuse()
-	return &writ{
+	readyFuse := latch.NewFuse()
+	wrt := &writ{
 		name:      name,
 		phase:     int32(WritPhase_Issued),
 		quitFuse:  quitFuse,
+		readyFuse: readyFuse,
 		doneFuse:  latch.NewFuse(),
-		svr:       &supervisor{name, quitFuse},
 		afterward: func() {},
 	}
+	if parent != nil {
+		wrt.tree = treeOf(parent)
+	}
+	wrt.svr = &supervisor{
+		name:          name,
+		ctrlChan_quit: quitFuse,
+		readyFuse:     readyFuse,
+		parent:        parent,
+		writ:          wrt,
+	}
+	return wrt
+}
+
+/*
+	Create a writ that's already terminal, reporting the given error.
+	Used when a manager needs to gather an error no agent actually raised.
+*/
+func newTombstone(name WritName, err error) *writ {
+	wrt := newWrit(name, nil)
+	wrt.phase = int32(WritPhase_Terminal | writFlag_Used)
+	wrt.err = err
+	wrt.quitFuse.Fire()
+	wrt.doneFuse.Fire()
+	return wrt
 }
 
 func (writ *writ) Name() WritName {
 	return writ.name
 }
 
-func (writ *writ) Run(fn Agent) (ret Writ) {
+func (writ *writ) Run(fn Agent) Writ {
+	return writ.RunE(func(super Supervisor) error {
+		fn(super)
+		return nil
+	})
+}
+
+func (writ *writ) RunE(fn AgentE) (ret Writ) {
 	ret = writ
 	var fly bool
 	for {
@@ -103,11 +180,27 @@ func (writ *writ) Run(fn Agent) (ret Writ) {
 		//  we have no choice but to quietly pack it in.
 		return
 	}
+	writ.agent = fn
+	started := time.Now()
+	writ.mu.Lock()
+	writ.started = started
+	writ.goroutine = currentGoroutineID()
+	writ.mu.Unlock()
+	metrics := writ.tree.metrics()
+	metrics.TaskStarted(writ.name)
+	writ.startSpan()
 	defer writ.afterward()
+	var panicked bool
 	meep.Try(func() {
-		fn(writ.svr)
+		if err := fn(writ.svr); err != nil {
+			writ.err = meep.Meep(
+				&ErrTaskFailed{Task: writ.Name()},
+				meep.Cause(err),
+			)
+		}
 	}, meep.TryPlan{
 		{ByType: &ErrTaskPanic{}, Handler: func(e error) {
+			panicked = true
 			writ.err = meep.Meep(
 				&ErrTaskPanic{Task: writ.Name()},
 				meep.Cause(e),
@@ -115,6 +208,7 @@ func (writ *writ) Run(fn Agent) (ret Writ) {
 			)
 		}},
 		{CatchAny: true, Handler: func(e error) {
+			panicked = true
 			writ.err = meep.Meep(
 				&ErrTaskPanic{Task: writ.Name()},
 				meep.Cause(e),
@@ -133,12 +227,73 @@ func (writ *writ) Run(fn Agent) (ret Writ) {
 			break
 		}
 	}
+	writ.stopDeadline()
+	writ.err = writ.deadlineErr(writ.err)
+	now := time.Now()
+	if panicked {
+		metrics.TaskPanicked(writ.name, writ.err)
+	}
+	metrics.TaskEnded(writ.name, writ.err, now.Sub(started))
+	writ.mu.Lock()
+	quitTime := writ.quitTime
+	writ.mu.Unlock()
+	if !quitTime.IsZero() {
+		metrics.ShutdownLatency(writ.name, now.Sub(quitTime))
+	}
+	writ.endSpan(panicked)
 	writ.doneFuse.Fire()
 	return
 }
 
+/*
+	Move an issued writ straight to terminal, reporting the given error,
+	without running anything -- but turning in to its manager just as
+	if an agent had run and returned.
+
+	Only for writs that nobody else will `Run`.
+*/
+func (writ *writ) turnIn(err error) {
+	for {
+		ph := WritPhase(atomic.LoadInt32(&writ.phase))
+		switch ph {
+		case WritPhase_Issued:
+		case WritPhase_Terminal:
+			return // cancelled; as with `Run`, there's nothing to do.
+		default:
+			panic(fmt.Sprintf("invalid writ state %d", ph))
+		}
+		if atomic.CompareAndSwapInt32(&writ.phase, int32(ph), int32(WritPhase_Terminal|writFlag_Used)) {
+			break
+		}
+	}
+	writ.stopDeadline()
+	writ.err = writ.deadlineErr(err)
+	writ.doneFuse.Fire()
+	writ.afterward()
+}
+
+/*
+	Returns the given error, or if there's none and the writ quit because
+	its deadline passed, an `ErrDeadlineExceeded`.
+*/
+func (writ *writ) deadlineErr(err error) error {
+	if err == nil && writ.reason().Kind == QuitReason_Deadline {
+		deadline, _ := writ.svr.Deadline()
+		err = meep.Meep(&ErrDeadlineExceeded{Task: writ.Name(), Deadline: deadline})
+	}
+	return err
+}
+
 func (writ *writ) Cancel() Writ {
-	writ.quitFuse.Fire()
+	return writ.cancel(QuitReason{Kind: QuitReason_Cancelled})
+}
+
+/*
+	As per `Cancel`, but recording the given reason (if the writ wasn't
+	already told to quit for some other reason).
+*/
+func (writ *writ) cancel(reason QuitReason) Writ {
+	writ.quit(reason)
 	var terminatedHere bool
 	for {
 		terminatedHere = false
@@ -164,11 +319,37 @@ func (writ *writ) Cancel() Writ {
 		}
 	}
 	if terminatedHere {
+		writ.stopDeadline()
 		writ.doneFuse.Fire()
 	}
 	return writ
 }
 
+/*
+	Record the reason (unless there already is one) and fire the quit fuse.
+	Doesn't touch the writ's phase; see `cancel` for that.
+*/
+func (writ *writ) quit(reason QuitReason) {
+	writ.mu.Lock()
+	first := writ.quitReason.Kind == QuitReason_None
+	if first {
+		writ.quitReason = reason
+		writ.quitTime = time.Now()
+	}
+	if first && writ.span != nil {
+		// Under the lock, so the span can't be ended meanwhile.
+		writ.span.AddEvent("quit", map[string]string{"reason": reason.String()})
+	}
+	writ.mu.Unlock()
+	writ.quitFuse.Fire()
+}
+
+func (writ *writ) reason() QuitReason {
+	writ.mu.Lock()
+	defer writ.mu.Unlock()
+	return writ.quitReason
+}
+
 func (writ *writ) Err() error {
 	<-writ.doneFuse.Selectable()
 	return writ.err
@@ -178,11 +359,18 @@ func (writ *writ) DoneCh() <-chan struct{} {
 	return writ.doneFuse.Selectable()
 }
 
+func (writ *writ) ReadyCh() <-chan struct{} {
+	return writ.readyFuse.Selectable()
+}
+
 ////
 
 type supervisor struct {
 	name          WritName
 	ctrlChan_quit latch.Fuse // typically a copy of the one from the manager.  the supervisor is all receiving end.
+	readyFuse     latch.Fuse // copy of the one from the writ.  the supervisor is the firing end.
+	parent        context.Context
+	writ          *writ // the writ we're supervising for.  for deadline and quit reason info.
 }
 
 func (super *supervisor) Name() WritName {
@@ -196,3 +384,37 @@ func (super *supervisor) QuitCh() <-chan struct{} {
 func (super *supervisor) Quit() bool {
 	return super.ctrlChan_quit.IsBlown()
 }
+
+func (super *supervisor) QuitReason() QuitReason {
+	if !super.ctrlChan_quit.IsBlown() {
+		return QuitReason{}
+	}
+	return super.writ.reason()
+}
+
+func (super *supervisor) Ready() {
+	super.readyFuse.Fire()
+}
+
+func (writ *writ) addManager(mgr *manager) {
+	if writ == nil {
+		return
+	}
+	writ.mu.Lock()
+	writ.managers = append(writ.managers, mgr)
+	writ.mu.Unlock()
+}
+
+func (writ *writ) removeManager(mgr *manager) {
+	if writ == nil {
+		return
+	}
+	writ.mu.Lock()
+	defer writ.mu.Unlock()
+	for i, m := range writ.managers {
+		if m == mgr {
+			writ.managers = append(writ.managers[:i], writ.managers[i+1:]...)
+			return
+		}
+	}
+}